@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// agendaWidth is the width of the printed card in characters
+const agendaWidth = 60
+
+// agendaSections gathers today's plan, other tasks due today and overdue
+// tasks, listing each task only once
+func agendaSections(tasks []Task, plan Plan, now time.Time) ([]string, [][]Task) {
+	var planned, due, overdue []Task
+	if plan.isFor(now) {
+		for _, id := range plan.IDs {
+			if task := findTask(tasks, id); task != nil {
+				planned = append(planned, *task)
+			}
+		}
+	}
+	for _, task := range tasks {
+		if findTask(planned, task.ID) != nil || task.Done {
+			continue
+		}
+		if isOverdue(task, now) {
+			overdue = append(overdue, task)
+		} else if isDueToday(task, now) {
+			due = append(due, task)
+		}
+	}
+	return []string{"Plan", "Due today", "Overdue"}, [][]Task{planned, due, overdue}
+}
+
+// agendaCard renders the day's agenda. The printable version has no colors,
+// fits on one page and leaves room for handwritten notes.
+func agendaCard(tasks []Task, plan Plan, now time.Time, printable bool) string {
+	var out strings.Builder
+	rule := strings.Repeat("=", agendaWidth)
+	date := logicalDate(now).Format("Monday, 2 January 2006")
+	if printable {
+		fmt.Fprintf(&out, "%s\n AGENDA  %s\n%s\n", rule, date, rule)
+	} else {
+		fmt.Fprintf(&out, "Agenda for %s\n", date)
+	}
+
+	titles, sections := agendaSections(tasks, plan, now)
+	empty := true
+	for i, section := range sections {
+		if len(section) == 0 {
+			continue
+		}
+		empty = false
+		fmt.Fprintf(&out, "\n %s\n", titles[i])
+		for _, task := range section {
+			box := "[ ]"
+			if task.Done {
+				box = "[x]"
+				if !printable {
+					box = green + box + reset
+				}
+			}
+			title := task.Title
+			if runes := []rune(title); printable && len(runes) > agendaWidth-10 {
+				title = string(runes[:agendaWidth-13]) + "..."
+			}
+			late := ""
+			if isOverdue(task, now) {
+				late = " (due " + task.Deadline.Format("2006-01-02") + ")"
+				if !printable {
+					late = red + late + reset
+				}
+			}
+			fmt.Fprintf(&out, " %s %s%s\n", box, title, late)
+		}
+	}
+	if empty {
+		out.WriteString("\n Nothing scheduled\n")
+	}
+	if printable {
+		out.WriteString("\n Notes\n")
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(&out, " %s\n", strings.Repeat("_", agendaWidth-2))
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// loadArchive reads archived tasks from archive.txt file
+func loadArchive() ([]Task, error) {
+	file, err := os.ReadFile(dataPath("archive.txt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Task{}, nil
+		}
+		return nil, err
+	}
+	var archived []Task
+	err = json.Unmarshal(file, &archived)
+	return archived, err
+}
+
+// saveArchive writes archived tasks to archive.txt file
+func saveArchive(archived []Task) error {
+	data, err := json.MarshalIndent(archived, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dataPath("archive.txt"), data, 0644)
+}
+
+// archivedTasks returns the archive without tasks that are active again
+// because archiving them was undone
+func archivedTasks(active []Task) ([]Task, error) {
+	archived, err := loadArchive()
+	if err != nil {
+		return nil, err
+	}
+	var shown []Task
+	for _, task := range archived {
+		if findTask(active, task.ID) == nil {
+			shown = append(shown, task)
+		}
+	}
+	return shown, nil
+}
+
+// archivedMaxID returns the highest ID in the archive so new tasks don't
+// reuse it, or 0 when there is no archive
+func archivedMaxID() int {
+	archived, _ := loadArchive()
+	maxID := 0
+	for _, task := range archived {
+		if task.ID > maxID {
+			maxID = task.ID
+		}
+	}
+	return maxID
+}
+
+// hasPendingSubtasks reports whether any subtask of id, at any depth, is
+// still open
+func hasPendingSubtasks(tasks []Task, id int) bool {
+	for _, task := range tasks {
+		if task.ParentID == id && (!task.Done || hasPendingSubtasks(tasks, task.ID)) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveDone moves finished tasks into archive.txt file. Done tasks with
+// open subtasks stay so the tree keeps its shape.
+func archiveDone(tasks []Task) ([]Task, int, error) {
+	archived, err := loadArchive()
+	if err != nil {
+		return tasks, 0, err
+	}
+	var active, moved []Task
+	for _, task := range tasks {
+		if task.Done && !hasPendingSubtasks(tasks, task.ID) {
+			moved = append(moved, task)
+		} else {
+			active = append(active, task)
+		}
+	}
+	if len(moved) == 0 {
+		return tasks, 0, nil
+	}
+	for _, task := range moved {
+		// A task archived again after an undo replaces its old copy
+		if old := findTask(archived, task.ID); old != nil {
+			*old = task
+		} else {
+			archived = append(archived, task)
+		}
+	}
+	if err := saveArchive(archived); err != nil {
+		return tasks, 0, err
+	}
+	if active == nil {
+		active = []Task{}
+	}
+	return active, len(moved), nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// flagSet holds the --flags given to a command
+type flagSet map[string][]string
+
+// parseFlags splits args into positional arguments and --flags. Flags named in
+// withValue take the following argument as their value; others are switches.
+func parseFlags(args []string, withValue ...string) ([]string, flagSet, error) {
+	takesValue := map[string]bool{}
+	for _, name := range withValue {
+		takesValue[name] = true
+	}
+
+	var positional []string
+	flags := flagSet{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") || arg == "--" {
+			positional = append(positional, arg)
+			continue
+		}
+		name := strings.TrimPrefix(arg, "--")
+		value := ""
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name, value = name[:eq], name[eq+1:]
+		} else if takesValue[name] {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("--%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		flags[name] = append(flags[name], value)
+	}
+	return positional, flags, nil
+}
+
+// has reports whether the flag was given
+func (f flagSet) has(name string) bool {
+	_, ok := f[name]
+	return ok
+}
+
+// get returns the last value given for the flag, or ""
+func (f flagSet) get(name string) string {
+	values := f[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
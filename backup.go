@@ -0,0 +1,133 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// backupFiles are the data files bundled into a backup. Sync tokens are
+// left out; providers ask to sign in again on a new machine.
+var backupFiles = []string{"tasks.txt", "archive.txt", "journal.txt", "config.json", "projects.json", "searches.json", "plan.json", "focus.json", "daylog.md", "history.json", caldavStatePath}
+
+// encrypted reports whether a backup path asks for age encryption
+func encrypted(path string) bool {
+	return strings.HasSuffix(path, ".age")
+}
+
+// runAge pipes data through the age tool, which asks for the passphrase
+// on the terminal itself
+func runAge(data []byte, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("age"); err != nil {
+		return nil, fmt.Errorf("encrypted backups need age (https://age-encryption.org) on the PATH")
+	}
+	var out bytes.Buffer
+	cmd := exec.Command("age", args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(data), &out, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("age: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+// exportBackup writes the data files into a gzipped tar archive, encrypting
+// it with a passphrase when the path ends in .age
+func exportBackup(path string) (int, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	count := 0
+	for _, name := range backupFiles {
+		data, err := os.ReadFile(dataPath(name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+		if err := archive.WriteHeader(header); err != nil {
+			return 0, err
+		}
+		if _, err := archive.Write(data); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if err := archive.Close(); err != nil {
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, err
+	}
+
+	data := buf.Bytes()
+	if encrypted(path) {
+		var err error
+		if data, err = runAge(data, "--passphrase"); err != nil {
+			return 0, err
+		}
+	}
+	return count, os.WriteFile(path, data, 0600)
+}
+
+// importBackup restores the data files from an archive made by
+// exportBackup, asking before it replaces existing tasks
+func importBackup(path string, force bool, in *bufio.Reader) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if encrypted(path) {
+		if data, err = runAge(data, "--decrypt"); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := os.Stat(dataPath("tasks.txt")); err == nil && !force && !confirm(in, "Replace the existing tasks with the backup?") {
+		return 0, fmt.Errorf("restore cancelled")
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("not a backup archive: %v", err)
+	}
+	archive := tar.NewReader(gz)
+	count := 0
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return count, err
+		}
+		name := filepath.Base(header.Name)
+		known := false
+		for _, file := range backupFiles {
+			known = known || file == name
+		}
+		if !known || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(archive)
+		if err != nil {
+			return count, err
+		}
+		if err := os.WriteFile(dataPath(name), content, 0644); err != nil {
+			return count, err
+		}
+		if name == "tasks.txt" {
+			if err := writeChecksum(content); err != nil {
+				return count, err
+			}
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// defaultDailyLimit is used when the config sets no daily_limit
+const defaultDailyLimit = 3
+
+// parseISOWeek returns the Monday starting an ISO week such as "2025-W24".
+// ISO weeks always start on Monday, whatever week_start says.
+func parseISOWeek(spec string) (time.Time, error) {
+	var year, week int
+	if _, err := fmt.Sscanf(spec, "%d-W%d", &year, &week); err != nil || week < 1 || week > 53 {
+		return time.Time{}, fmt.Errorf("invalid week %q, expected YYYY-Www", spec)
+	}
+	// January 4th is always in week 1
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.Local)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, (week-1)*7), nil
+}
+
+// flexible reports whether a task's deadline may be moved by balancing.
+// Deadlines of synced tasks belong to their source and would come back on
+// the next sync.
+func flexible(task Task) bool {
+	return !task.Done && len(task.Remotes) == 0
+}
+
+// move is a suggested deadline change
+type move struct {
+	ID       int
+	From, To time.Time
+}
+
+// balanceWeek suggests moving flexible tasks off days with more deadlines
+// than the daily limit onto the lightest days of the week still ahead
+func balanceWeek(tasks []Task, start time.Time, limit int, now time.Time) []move {
+	today := logicalDate(now)
+	var days []time.Time
+	load := map[time.Time][]Task{}
+	for i := 0; i < 7; i++ {
+		day := dateOf(start.AddDate(0, 0, i))
+		days = append(days, day)
+	}
+	for _, task := range tasks {
+		if !task.Done && !task.Deadline.IsZero() {
+			day := dateOf(task.Deadline)
+			load[day] = append(load[day], task)
+		}
+	}
+
+	var moves []move
+	for _, day := range days {
+		// Move the most recently added tasks first
+		busy := load[day]
+		sort.SliceStable(busy, func(i, j int) bool { return busy[i].ID > busy[j].ID })
+		for i := 0; len(load[day]) > limit && i < len(busy); i++ {
+			if !flexible(busy[i]) {
+				continue
+			}
+			var target time.Time
+			for _, candidate := range days {
+				if candidate.Before(today) || candidate.Equal(day) || len(load[candidate]) >= limit {
+					continue
+				}
+				if target.IsZero() || len(load[candidate]) < len(load[target]) {
+					target = candidate
+				}
+			}
+			if target.IsZero() {
+				break
+			}
+			moves = append(moves, move{ID: busy[i].ID, From: day, To: target})
+			load[target] = append(load[target], busy[i])
+			load[day] = removeTask(load[day], busy[i].ID)
+		}
+	}
+	return moves
+}
+
+// removeTask returns tasks without the one with the given ID
+func removeTask(tasks []Task, id int) []Task {
+	var kept []Task
+	for _, task := range tasks {
+		if task.ID != id {
+			kept = append(kept, task)
+		}
+	}
+	return kept
+}
+
+// balanceCommand prints suggested moves for a week and applies them after
+// confirmation when apply is set
+func balanceCommand(tasks []Task, week string, apply bool, in io.Reader, now time.Time) ([]Task, error) {
+	start := weekStart(logicalDate(now))
+	if week != "" {
+		var err error
+		if start, err = parseISOWeek(week); err != nil {
+			return tasks, err
+		}
+	}
+	limit := config.DailyLimit
+	if limit <= 0 {
+		limit = defaultDailyLimit
+	}
+
+	moves := balanceWeek(tasks, start, limit, now)
+	if len(moves) == 0 {
+		fmt.Printf("%sWeek of %s is balanced (at most %d deadlines a day)%s\n", green, start.Format("2006-01-02"), limit, reset)
+		return tasks, nil
+	}
+	for _, m := range moves {
+		fmt.Printf("#%d: %s  %s -> %s\n", m.ID, findTask(tasks, m.ID).Title, m.From.Format("Mon 01-02"), m.To.Format("Mon 01-02"))
+	}
+	if !apply {
+		fmt.Println("Run with --apply to make these changes")
+		return tasks, nil
+	}
+	if !confirm(bufio.NewReader(in), fmt.Sprintf("Move %d tasks?", len(moves))) {
+		fmt.Println(yellow + "Nothing changed" + reset)
+		return tasks, nil
+	}
+	for _, m := range moves {
+		findTask(tasks, m.ID).Deadline = deadlineOn(m.To)
+	}
+	fmt.Printf("%sMoved %d tasks%s\n", green, len(moves), reset)
+	return tasks, nil
+}
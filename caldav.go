@@ -0,0 +1,538 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// CalDAVConfig configures "sync caldav" against a calendar collection on a
+// CalDAV server such as Nextcloud or Radicale
+type CalDAVConfig struct {
+	// URL is the calendar collection, e.g.
+	// https://cloud.example.com/remote.php/dav/calendars/me/tasks/
+	URL  string `json:"url,omitempty"`
+	User string `json:"user,omitempty"`
+	// PasswordEnv names the environment variable holding the password or
+	// app password (CALDAV_PASSWORD if empty)
+	PasswordEnv string `json:"password_env,omitempty"`
+}
+
+// caldavEntry is what the mapping table remembers about a synced VTODO:
+// its etag on the server and the task as it was when last synced
+type caldavEntry struct {
+	ETag   string `json:"etag"`
+	Synced string `json:"synced"`
+}
+
+// caldavTodo is a VTODO resource on the server
+type caldavTodo struct {
+	URL      string // absolute URL of the resource
+	ETag     string
+	Data     string
+	UID      string
+	Summary  string
+	Deadline time.Time
+	Done     bool
+}
+
+// errCalDAVChanged is returned when a write is refused because the
+// resource changed on the server since it was read
+var errCalDAVChanged = errors.New("changed on the server")
+
+// caldavClient talks to one calendar collection
+type caldavClient struct {
+	base     *url.URL
+	user     string
+	password string
+}
+
+// newCalDAVClient validates the CalDAV settings
+func newCalDAVClient(cfg CalDAVConfig) (*caldavClient, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("set caldav.url in config or pass --url")
+	}
+	if cfg.PasswordEnv == "" {
+		cfg.PasswordEnv = "CALDAV_PASSWORD"
+	}
+	if !strings.HasSuffix(cfg.URL, "/") {
+		cfg.URL += "/"
+	}
+	base, err := url.Parse(cfg.URL)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid caldav url %q", cfg.URL)
+	}
+	password := providerToken(cfg.PasswordEnv, "caldav "+cfg.URL)
+	if password == "" {
+		return nil, fmt.Errorf("caldav password not set, export %s or run \"auth login caldav\"", cfg.PasswordEnv)
+	}
+	return &caldavClient{base: base, user: cfg.User, password: password}, nil
+}
+
+// do sends a request and returns the response body. A 412 reply becomes
+// errCalDAVChanged; other failures are reported with the server's message.
+func (c *caldavClient) do(method, target string, header http.Header, body []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return resp, data, errCalDAVChanged
+	}
+	if resp.StatusCode >= 300 {
+		if len(data) > 512 {
+			data = data[:512]
+		}
+		return resp, data, fmt.Errorf("%s %s: %s: %s", method, req.URL.Path, resp.Status, bytes.TrimSpace(data))
+	}
+	return resp, data, nil
+}
+
+// caldavQuery asks for the etag and content of every VTODO
+const caldavQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter>
+</c:calendar-query>`
+
+// davMultistatus is the reply to a REPORT
+type davMultistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ETag string `xml:"DAV: getetag"`
+				Data string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// todos returns every VTODO in the collection
+func (c *caldavClient) todos() ([]caldavTodo, error) {
+	header := http.Header{"Depth": {"1"}, "Content-Type": {"application/xml; charset=utf-8"}}
+	_, data, err := c.do("REPORT", c.base.String(), header, []byte(caldavQuery))
+	if err != nil {
+		return nil, err
+	}
+	var reply davMultistatus
+	if err := xml.Unmarshal(data, &reply); err != nil {
+		return nil, fmt.Errorf("invalid REPORT reply: %v", err)
+	}
+	var todos []caldavTodo
+	for _, r := range reply.Responses {
+		href, err := c.base.Parse(r.Href)
+		if err != nil {
+			return nil, fmt.Errorf("invalid href %q", r.Href)
+		}
+		for _, ps := range r.Propstat {
+			if ps.Prop.Data == "" || !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			todo := parseVTODO(ps.Prop.Data)
+			todo.URL, todo.ETag, todo.Data = href.String(), ps.Prop.ETag, ps.Prop.Data
+			todos = append(todos, todo)
+		}
+	}
+	return todos, nil
+}
+
+// put writes a calendar object. An empty etag creates it and fails if it
+// already exists; otherwise it is only replaced if still at that etag.
+// It returns the new etag, which servers may leave out.
+func (c *caldavClient) put(target, etag string, data []byte) (string, error) {
+	header := http.Header{"Content-Type": {"text/calendar; charset=utf-8"}}
+	if etag == "" {
+		header.Set("If-None-Match", "*")
+	} else {
+		header.Set("If-Match", etag)
+	}
+	resp, _, err := c.do("PUT", target, header, data)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// remove deletes a calendar object if it is still at etag
+func (c *caldavClient) remove(target, etag string) error {
+	_, _, err := c.do("DELETE", target, http.Header{"If-Match": {etag}}, nil)
+	return err
+}
+
+// icsUnescape reverses icsEscape
+func icsUnescape(text string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(text)
+}
+
+// icsLines unfolds calendar data into content lines
+func icsLines(data string) []string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.NewReplacer("\n ", "", "\n\t", "").Replace(data)
+	return strings.Split(strings.TrimRight(data, "\n"), "\n")
+}
+
+// icsProperty splits a content line into its name and value, dropping any
+// parameters
+func icsProperty(line string) (string, string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), ""
+	}
+	name := line[:colon]
+	if semi := strings.Index(name, ";"); semi >= 0 {
+		name = name[:semi]
+	}
+	return strings.ToUpper(name), line[colon+1:]
+}
+
+// parseVTODO reads the fields that are synced from the first VTODO in
+// calendar data
+func parseVTODO(data string) caldavTodo {
+	var todo caldavTodo
+	inside := false
+	for _, line := range icsLines(data) {
+		name, value := icsProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VTODO":
+			inside = true
+		case name == "END" && value == "VTODO":
+			return todo
+		case !inside:
+		case name == "UID":
+			todo.UID = value
+		case name == "SUMMARY":
+			todo.Summary = icsUnescape(value)
+		case name == "DUE" && len(value) >= 8:
+			todo.Deadline, _ = time.Parse("20060102", value[:8])
+		case name == "STATUS":
+			todo.Done = todo.Done || value == "COMPLETED"
+		case name == "COMPLETED":
+			todo.Done = true
+		}
+	}
+	return todo
+}
+
+// caldavSynced properties are the ones written from a task; the rest of an
+// existing VTODO is kept as the server has it
+var caldavSynced = map[string]bool{
+	"SUMMARY": true, "DUE": true, "STATUS": true, "COMPLETED": true,
+	"PERCENT-COMPLETE": true, "DTSTAMP": true, "LAST-MODIFIED": true,
+}
+
+// caldavObject renders a task as a VTODO. When data holds the task's
+// current calendar object only the synced properties are replaced in it.
+func caldavObject(task Task, data string, now time.Time) []byte {
+	stamp := now.UTC().Format("20060102T150405Z")
+	var fields []string
+	fields = append(fields, "DTSTAMP:"+stamp, "LAST-MODIFIED:"+stamp, "SUMMARY:"+icsEscape(task.Title))
+	if !task.Deadline.IsZero() {
+		fields = append(fields, "DUE;VALUE=DATE:"+task.Deadline.Format("20060102"))
+	}
+	if task.Done {
+		fields = append(fields, "STATUS:COMPLETED", "PERCENT-COMPLETE:100")
+		if !task.CompletedAt.IsZero() {
+			fields = append(fields, "COMPLETED:"+task.CompletedAt.UTC().Format("20060102T150405Z"))
+		}
+	} else {
+		fields = append(fields, "STATUS:NEEDS-ACTION")
+	}
+
+	var out strings.Builder
+	if data == "" {
+		for _, line := range []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//todo//CLI To-Do List//EN", "BEGIN:VTODO", "UID:" + task.UID + "@todo"} {
+			out.WriteString(icsFold(line))
+		}
+		for _, field := range fields {
+			out.WriteString(icsFold(field))
+		}
+		out.WriteString(icsFold("END:VTODO"))
+		out.WriteString(icsFold("END:VCALENDAR"))
+		return []byte(out.String())
+	}
+
+	inside, replaced := false, false
+	for _, line := range icsLines(data) {
+		name, value := icsProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VTODO" && !replaced:
+			inside = true
+		case name == "END" && value == "VTODO" && inside:
+			for _, field := range fields {
+				out.WriteString(icsFold(field))
+			}
+			inside, replaced = false, true
+		case inside && caldavSynced[name]:
+			continue
+		}
+		out.WriteString(icsFold(line))
+	}
+	return []byte(out.String())
+}
+
+// caldavFingerprint sums up the synced fields of a task, to tell whether
+// it changed locally since the last sync
+func caldavFingerprint(task Task) string {
+	deadline := ""
+	if !task.Deadline.IsZero() {
+		deadline = task.Deadline.Format("2006-01-02")
+	}
+	return fmt.Sprintf("%s|%s|%t", task.Title, deadline, task.Done)
+}
+
+// caldavStatePath is the mapping table, keyed by calendar URL and then by
+// resource URL
+const caldavStatePath = "caldav.json"
+
+// loadCalDAVState reads the mapping table
+func loadCalDAVState() (map[string]map[string]caldavEntry, error) {
+	state := map[string]map[string]caldavEntry{}
+	data, err := os.ReadFile(dataPath(caldavStatePath))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%s: %v", caldavStatePath, err)
+	}
+	return state, nil
+}
+
+// saveCalDAVState writes the mapping table
+func saveCalDAVState(state map[string]map[string]caldavEntry) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dataPath(caldavStatePath), data, 0644)
+}
+
+// applyVTODO copies a VTODO's synced fields onto the task with id. A VTODO
+// completed on the server completes the task the way "done" does, which
+// may add tasks, so pointers into tasks must be looked up again.
+func applyVTODO(tasks []Task, id int, todo caldavTodo) []Task {
+	task := findTask(tasks, id)
+	if todo.Summary != "" {
+		task.Title = todo.Summary
+	}
+	task.Deadline = todo.Deadline
+	switch {
+	case todo.Done && !task.Done:
+		tasks, _ = completeRemote(tasks, id)
+	case !todo.Done && task.Done:
+		task.setDone(false)
+	}
+	return tasks
+}
+
+// caldavLink returns the resource a task is linked to in a calendar, or ""
+func caldavLink(task Task, calendar string) string {
+	for _, r := range task.Remotes {
+		if r.Source == "caldav" && strings.HasPrefix(r.ID, calendar) {
+			return r.ID
+		}
+	}
+	return ""
+}
+
+// syncCalDAV syncs both ways with a calendar collection. Etags tell which
+// VTODOs changed on the server and the mapping table which tasks changed
+// here since the last sync; when both did, the server's version wins and
+// the conflict is reported. Deletions flow both ways. Writes are
+// conditional on the etag, so a VTODO edited meanwhile is left for the
+// next sync.
+func syncCalDAV(tasks []Task, cfg CalDAVConfig) ([]Task, error) {
+	client, err := newCalDAVClient(cfg)
+	if err != nil {
+		return tasks, err
+	}
+	todos, err := client.todos()
+	if err != nil {
+		return tasks, err
+	}
+	state, err := loadCalDAVState()
+	if err != nil {
+		return tasks, err
+	}
+	calendar := client.base.String()
+	entries := state[calendar]
+	if entries == nil {
+		entries = map[string]caldavEntry{}
+		state[calendar] = entries
+	}
+	// The mapping table is saved even when the sync fails partway, so the
+	// links made so far keep their etags
+	fail := func(err error) ([]Task, error) {
+		saveCalDAVState(state)
+		return tasks, err
+	}
+
+	now := time.Now()
+	var added, updated, pushed, deleted, unlinked, conflicts, skipped int
+	seen := map[string]bool{}
+	for _, todo := range todos {
+		seen[todo.URL] = true
+		remote := Remote{Source: "caldav", ID: todo.URL}
+		entry, known := entries[todo.URL]
+		remoteChanged := !known || entry.ETag != todo.ETag
+		local := findRemote(tasks, remote)
+
+		if local == nil {
+			if known && !remoteChanged {
+				// The task was deleted or archived here. Finished VTODOs
+				// stay on the server as history.
+				if !todo.Done {
+					if err := client.remove(todo.URL, todo.ETag); err == errCalDAVChanged {
+						skipped++
+						continue
+					} else if err != nil {
+						return fail(err)
+					}
+					deleted++
+				}
+				delete(entries, todo.URL)
+				continue
+			}
+			// A VTODO this tool created whose link was never saved is
+			// linked back to its task rather than imported again
+			if task := findUID(tasks, strings.TrimSuffix(todo.UID, "@todo")); task != nil && todo.UID != "" && caldavLink(*task, calendar) == "" {
+				task.Remotes = append(task.Remotes, remote)
+				entries[todo.URL] = caldavEntry{ETag: todo.ETag, Synced: caldavFingerprint(*task)}
+				continue
+			}
+			if todo.Done || todo.Summary == "" {
+				continue
+			}
+			var n int
+			tasks, n, _ = applyRemoteItems(tasks, []remoteItem{{Remote: remote, Title: todo.Summary, Deadline: todo.Deadline}})
+			added += n
+			local = findRemote(tasks, remote)
+			if n == 0 {
+				// Linked to the same item from another source
+				tasks = applyVTODO(tasks, local.ID, todo)
+				local = findRemote(tasks, remote)
+			}
+			entries[todo.URL] = caldavEntry{ETag: todo.ETag, Synced: caldavFingerprint(*local)}
+			continue
+		}
+
+		localChanged := known && caldavFingerprint(*local) != entry.Synced
+		switch {
+		case remoteChanged && localChanged:
+			fmt.Printf("%sConflict: task %d %q changed here and on the server, kept the server's version%s\n",
+				yellow, local.ID, local.Title, reset)
+			tasks = applyVTODO(tasks, local.ID, todo)
+			local = findRemote(tasks, remote)
+			conflicts++
+		case remoteChanged:
+			before := caldavFingerprint(*local)
+			tasks = applyVTODO(tasks, local.ID, todo)
+			local = findRemote(tasks, remote)
+			if caldavFingerprint(*local) != before {
+				updated++
+			}
+		case localChanged:
+			etag, err := client.put(todo.URL, todo.ETag, caldavObject(*local, todo.Data, now))
+			if err == errCalDAVChanged {
+				skipped++
+				continue
+			}
+			if err != nil {
+				return fail(err)
+			}
+			todo.ETag = etag
+			pushed++
+		}
+		entries[todo.URL] = caldavEntry{ETag: todo.ETag, Synced: caldavFingerprint(*local)}
+	}
+
+	// VTODOs gone from the server take their tasks with them, unless the
+	// task changed here since the last sync or is linked elsewhere too;
+	// then it is only unlinked
+	var gone []int
+	for i := range tasks {
+		var kept []Remote
+		for _, r := range tasks[i].Remotes {
+			if r.Source != "caldav" || !strings.HasPrefix(r.ID, calendar) || seen[r.ID] {
+				kept = append(kept, r)
+				continue
+			}
+			entry, known := entries[r.ID]
+			if known && len(tasks[i].Remotes) == 1 && caldavFingerprint(tasks[i]) == entry.Synced {
+				gone = append(gone, tasks[i].ID)
+			} else {
+				unlinked++
+			}
+		}
+		tasks[i].Remotes = kept
+	}
+	for _, id := range gone {
+		tasks, _ = deleteTask(tasks, id)
+	}
+	for target := range entries {
+		if !seen[target] {
+			delete(entries, target)
+		}
+	}
+
+	created := 0
+	for i := range tasks {
+		if tasks[i].Done || len(tasks[i].Remotes) > 0 {
+			continue
+		}
+		if tasks[i].UID == "" {
+			tasks[i].UID = newUID()
+		}
+		target, _ := client.base.Parse(url.PathEscape(tasks[i].UID) + ".ics")
+		etag, err := client.put(target.String(), "", caldavObject(tasks[i], "", now))
+		if err == errCalDAVChanged {
+			// Created by an earlier sync that failed before saving the
+			// link; the next sync brings in the server's copy
+			etag, err = "", nil
+		}
+		if err != nil {
+			return fail(err)
+		}
+		tasks[i].Remotes = append(tasks[i].Remotes, Remote{Source: "caldav", ID: target.String()})
+		entries[target.String()] = caldavEntry{ETag: etag, Synced: caldavFingerprint(tasks[i])}
+		created++
+	}
+
+	if err := saveCalDAVState(state); err != nil {
+		return tasks, err
+	}
+	fmt.Printf("%sCalDAV: %d added, %d updated, %d removed, %d unlinked, %d pushed, %d created, %d deleted remotely%s\n",
+		green, added, updated, len(gone), unlinked, pushed, created, deleted, reset)
+	if conflicts > 0 || skipped > 0 {
+		fmt.Printf("%sCalDAV: %d conflicts resolved for the server, %d changed during sync and left for next time%s\n",
+			yellow, conflicts, skipped, reset)
+	}
+	return tasks, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseVTODO(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		summary  string
+		deadline string
+		done     bool
+	}{
+		{"plain", "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:1\r\nSUMMARY:Pay rent\r\nDUE;VALUE=DATE:20260310\r\nEND:VTODO\r\nEND:VCALENDAR\r\n",
+			"Pay rent", "2026-03-10", false},
+		{"date-time due and LF endings", "BEGIN:VCALENDAR\nBEGIN:VTODO\nSUMMARY:Call\\, then write\nDUE;TZID=Europe/Berlin:20260311T090000\nEND:VTODO\nEND:VCALENDAR\n",
+			"Call, then write", "2026-03-11", false},
+		{"folded summary", "BEGIN:VTODO\r\nSUMMARY:A very long\r\n  title\r\nEND:VTODO\r\n",
+			"A very long title", "", false},
+		{"completed status", "BEGIN:VTODO\r\nSUMMARY:x\r\nSTATUS:COMPLETED\r\nEND:VTODO\r\n", "x", "", true},
+		{"completed stamp", "BEGIN:VTODO\r\nSUMMARY:x\r\nCOMPLETED:20260301T100000Z\r\nEND:VTODO\r\n", "x", "", true},
+		{"properties outside the VTODO", "BEGIN:VCALENDAR\r\nSUMMARY:calendar\r\nBEGIN:VTODO\r\nEND:VTODO\r\nBEGIN:VTODO\r\nSUMMARY:second\r\nEND:VTODO\r\n",
+			"", "", false},
+	}
+	for _, tt := range tests {
+		todo := parseVTODO(tt.data)
+		deadline := ""
+		if !todo.Deadline.IsZero() {
+			deadline = todo.Deadline.Format("2006-01-02")
+		}
+		if todo.Summary != tt.summary || deadline != tt.deadline || todo.Done != tt.done {
+			t.Errorf("%s: got %q due %q done %t, want %q due %q done %t",
+				tt.name, todo.Summary, deadline, todo.Done, tt.summary, tt.deadline, tt.done)
+		}
+	}
+}
+
+func TestCalDAVObject(t *testing.T) {
+	now := time.Date(2026, 3, 5, 8, 0, 0, 0, time.UTC)
+	deadline, _ := time.Parse("2006-01-02", "2026-03-12")
+	task := Task{ID: 1, Title: "Water plants; all of them", Deadline: deadline, UID: "u1"}
+
+	created := string(caldavObject(task, "", now))
+	for _, want := range []string{"UID:u1@todo\r\n", "SUMMARY:Water plants\\; all of them\r\n", "DUE;VALUE=DATE:20260312\r\n", "STATUS:NEEDS-ACTION\r\n"} {
+		if !strings.Contains(created, want) {
+			t.Errorf("new VTODO lacks %q:\n%s", want, created)
+		}
+	}
+	if todo := parseVTODO(created); todo.Summary != task.Title || !todo.Deadline.Equal(deadline) || todo.Done {
+		t.Errorf("reading back a new VTODO gave %+v", todo)
+	}
+
+	// Editing keeps what the server added and replaces the synced fields
+	server := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:u1@todo\r\nSUMMARY:Old title\r\nDUE;VALUE=DATE:20260301\r\n" +
+		"X-APPLE-SORT-ORDER:42\r\nSTATUS:NEEDS-ACTION\r\nEND:VTODO\r\nBEGIN:VTIMEZONE\r\nSUMMARY:kept\r\nEND:VTIMEZONE\r\nEND:VCALENDAR\r\n"
+	task.setDone(true)
+	task.CompletedAt = time.Date(2026, 3, 4, 18, 30, 0, 0, time.UTC)
+	edited := string(caldavObject(task, server, now))
+	for _, want := range []string{"X-APPLE-SORT-ORDER:42\r\n", "STATUS:COMPLETED\r\n", "COMPLETED:20260304T183000Z\r\n", "SUMMARY:kept\r\n"} {
+		if !strings.Contains(edited, want) {
+			t.Errorf("edited VTODO lacks %q:\n%s", want, edited)
+		}
+	}
+	for _, gone := range []string{"Old title", "20260301", "NEEDS-ACTION"} {
+		if strings.Contains(edited, gone) {
+			t.Errorf("edited VTODO still has %q:\n%s", gone, edited)
+		}
+	}
+	if strings.Count(edited, "SUMMARY:") != 2 || strings.Count(edited, "UID:") != 1 {
+		t.Errorf("edited VTODO has duplicated properties:\n%s", edited)
+	}
+}
+
+func TestSyncCalDAVReplay(t *testing.T) {
+	t.Setenv("CALDAV_PASSWORD", "secret")
+	withConfig(t, Config{})
+	calendar := "https://dav.example.com/cal/tasks/"
+	tasks := []Task{
+		{ID: 1, Title: "Pay rent", Remotes: []Remote{{Source: "caldav", ID: calendar + "b.ics"}}},
+		{ID: 3, Title: "Call mom", UID: "u3"},
+		{ID: 4, Title: "Old errand", Remotes: []Remote{{Source: "caldav", ID: calendar + "c.ics"}}},
+	}
+	state := map[string]map[string]caldavEntry{calendar: {
+		calendar + "b.ics": {ETag: `"b1"`, Synced: caldavFingerprint(tasks[0])},
+		calendar + "c.ics": {ETag: `"c1"`, Synced: caldavFingerprint(tasks[2])},
+	}}
+	if err := saveCalDAVState(state); err != nil {
+		t.Fatal(err)
+	}
+	tasks = replay(t, tasks, "caldav", "--url", calendar, "--user", "me", "--replay-http", "testdata/caldav.jsonl")
+
+	if rent := findTask(tasks, 1); rent == nil || !rent.Done {
+		t.Errorf("VTODO completed on the server did not complete #1: %+v", rent)
+	}
+	if findTask(tasks, 4) != nil {
+		t.Error("#4 was kept although its VTODO was deleted on the server")
+	}
+	plants := findRemote(tasks, Remote{Source: "caldav", ID: calendar + "a.ics"})
+	if plants == nil || plants.Title != "Water plants" || plants.Deadline.Format("2006-01-02") != "2026-03-12" {
+		t.Errorf("new VTODO not imported: %+v", plants)
+	}
+	if mom := findTask(tasks, 3); len(mom.Remotes) != 1 || mom.Remotes[0].ID != calendar+"u3.ics" {
+		t.Errorf("local task not pushed: %+v", mom.Remotes)
+	}
+
+	state, err := loadCalDAVState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := state[calendar]
+	if len(entries) != 3 || entries[calendar+"b.ics"].ETag != `"b2"` || entries[calendar+"u3.ics"].ETag != `"u1"` {
+		t.Errorf("mapping table after sync: %+v", entries)
+	}
+}
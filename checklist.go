@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// gitOutput runs git in dir and returns its trimmed output
+func gitOutput(dir string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// repoWebURL turns a GitHub/GitLab style remote URL into its web address
+func repoWebURL(remote string) string {
+	remote = strings.TrimSuffix(remote, ".git")
+	if strings.HasPrefix(remote, "git@") {
+		// git@github.com:owner/repo
+		remote = "https://" + strings.Replace(strings.TrimPrefix(remote, "git@"), ":", "/", 1)
+	}
+	if !strings.HasPrefix(remote, "https://") && !strings.HasPrefix(remote, "http://") {
+		return ""
+	}
+	return remote
+}
+
+// codePermalink links to a line of a file at the current commit of the
+// repository it lives in, or returns "" when that isn't possible
+func codePermalink(file string, line int) string {
+	dir := filepath.Dir(file)
+	top, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return ""
+	}
+	origin, err := gitOutput(dir, "remote", "get-url", "origin")
+	if err != nil {
+		return ""
+	}
+	commit, err := gitOutput(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return ""
+	}
+	web := repoWebURL(origin)
+	rel, err := filepath.Rel(top, file)
+	if web == "" || err != nil {
+		return ""
+	}
+	blob := "/blob/"
+	if strings.Contains(web, "gitlab") {
+		blob = "/-/blob/"
+	}
+	return web + blob + commit + "/" + filepath.ToSlash(rel) + "#L" + strconv.Itoa(line)
+}
+
+// checklistLinks renders a task's remote references as Markdown links,
+// using commit permalinks for code comments
+func checklistLinks(task Task) string {
+	var links []string
+	for _, remote := range task.Remotes {
+		link := remote.URL
+		label := remote.ID
+		if remote.Source == "code" {
+			file := remoteFile(remote)
+			line, _ := strconv.Atoi(strings.TrimPrefix(remote.ID, file+":"))
+			label = filepath.Base(file) + ":" + strconv.Itoa(line)
+			if permalink := codePermalink(file, line); permalink != "" {
+				link = permalink
+			}
+		}
+		if link == "" {
+			links = append(links, label)
+		} else {
+			links = append(links, fmt.Sprintf("[%s](%s)", label, link))
+		}
+	}
+	if len(links) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(links, ", ") + ")"
+}
+
+// exportChecklist renders tasks as a Markdown review checklist
+func exportChecklist(tasks []Task) []byte {
+	var out strings.Builder
+	for _, task := range tasks {
+		box := "[ ]"
+		if task.Done {
+			box = "[x]"
+		}
+		fmt.Fprintf(&out, "- %s %s%s\n", box, task.Title, checklistLinks(task))
+	}
+	return []byte(strings.TrimSuffix(out.String(), "\n"))
+}
+
+// exportMarkdown renders tasks as Markdown checklists under a heading for
+// open and for done tasks, ready to paste into an issue or notes app
+func exportMarkdown(tasks []Task) []byte {
+	var out strings.Builder
+	for _, section := range []struct {
+		heading string
+		done    bool
+	}{{"Open", false}, {"Done", true}} {
+		var lines []string
+		for _, task := range tasks {
+			if task.Done != section.done {
+				continue
+			}
+			box := "[ ]"
+			if task.Done {
+				box = "[x]"
+			}
+			due := ""
+			if !task.Deadline.IsZero() {
+				due = " (due " + task.Deadline.Format("2006-01-02") + ")"
+			}
+			lines = append(lines, fmt.Sprintf("- %s %s%s", box, task.Title, due))
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "## %s\n\n%s\n", section.heading, strings.Join(lines, "\n"))
+	}
+	return []byte(strings.TrimSuffix(out.String(), "\n"))
+}
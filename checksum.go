@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+)
+
+// errChecksum means tasks.txt no longer matches the checksum saved with it
+var errChecksum = errors.New("tasks.txt does not match its checksum; it may be corrupted or was edited outside todo")
+
+// checksum returns the hex SHA-256 of data
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeChecksum records the checksum of the tasks file contents in
+// tasks.sha256 file
+func writeChecksum(data []byte) error {
+	return os.WriteFile(dataPath("tasks.sha256"), []byte(checksum(data)+"\n"), 0644)
+}
+
+// verifyChecksum compares the tasks file contents with tasks.sha256 file.
+// Data saved before checksums existed has no file and always passes.
+func verifyChecksum(data []byte) error {
+	saved, err := os.ReadFile(dataPath("tasks.sha256"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if strings.TrimSpace(string(saved)) != checksum(data) {
+		return errChecksum
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config holds user settings read from config.json
+type Config struct {
+	// DayEnd is the clock time ("HH:MM") at which one day turns into the
+	// next, e.g. "03:00" for night owls. Defaults to midnight.
+	DayEnd string `json:"day_end,omitempty"`
+	// DeadlineTime is the clock time attached to date-only deadlines.
+	// Defaults to the end of the day.
+	DeadlineTime string `json:"deadline_time,omitempty"`
+	// DailyLimit is how many deadlines "balance" allows on one day.
+	// Defaults to 3.
+	DailyLimit int `json:"daily_limit,omitempty"`
+	// WeekStart is "monday" or "sunday". Defaults to the locale's
+	// convention.
+	WeekStart string `json:"week_start,omitempty"`
+	// DefaultProject is the project add puts tasks in without --project
+	DefaultProject string `json:"default_project,omitempty"`
+	// Accessible adds text markers such as [OVERDUE] next to colors and
+	// uses a palette that works with red-green color blindness
+	Accessible bool `json:"accessible,omitempty"`
+	// QuietHours hold back "remind" notifications
+	QuietHours QuietHours `json:"quiet_hours"`
+	// Digest batches "remind" notifications into digests at set times
+	Digest DigestConfig `json:"digest"`
+	// WIPLimit caps how many tasks may be in progress (being tracked) at
+	// once; 0 means no limit
+	WIPLimit int `json:"wip_limit,omitempty"`
+	// RemindBefore is how long before a deadline "remind" notifies, e.g.
+	// "30m" or "2h". Defaults to an hour.
+	RemindBefore string `json:"remind_before,omitempty"`
+	// Escalation replaces that single reminder with a chain of them for
+	// tasks of the given priority levels, e.g. "high"
+	Escalation map[string]EscalationConfig `json:"escalation,omitempty"`
+	// Columns are optional columns shown in task lists, e.g. ["countdown"]
+	Columns []string `json:"columns,omitempty"`
+	// Jira lists the Jira instances available to "sync jira" by name
+	Jira map[string]JiraConfig `json:"jira,omitempty"`
+	// GitLab configures "sync gitlab"
+	GitLab GitLabConfig `json:"gitlab"`
+	// Microsoft configures "sync mstodo"
+	Microsoft MicrosoftConfig `json:"microsoft"`
+	// Vault configures "sync vault"
+	Vault VaultConfig `json:"vault"`
+	// CalDAV configures "sync caldav"
+	CalDAV CalDAVConfig `json:"caldav"`
+	// Google configures "sync gcal"
+	Google GoogleConfig `json:"google"`
+	// Plugins holds settings passed as-is to provider plugins, by name
+	Plugins map[string]json.RawMessage `json:"plugins,omitempty"`
+}
+
+// QuietHours is a do-not-disturb window: every day between From and To
+// ("HH:MM", may wrap past midnight) and all day on Days
+type QuietHours struct {
+	From string   `json:"from,omitempty"`
+	To   string   `json:"to,omitempty"`
+	Days []string `json:"days,omitempty"`
+}
+
+// DigestConfig sends reminders as a digest at the given times ("HH:MM")
+// instead of one notification per task. Priorities limits the digest to
+// tasks of those levels, leaving the rest to be alerted right away. Email,
+// when set, also mails each digest through sendmail.
+type DigestConfig struct {
+	Times      []string `json:"times,omitempty"`
+	Priorities []string `json:"priorities,omitempty"`
+	Email      string   `json:"email,omitempty"`
+}
+
+// EscalationConfig is a chain of reminders: one as each lead in Before
+// ("7d", "1d", "2h") is reached, then one every Overdue ("1h") after the
+// deadline has passed, until the task is done or snoozed
+type EscalationConfig struct {
+	Before  []string `json:"before,omitempty"`
+	Overdue string   `json:"overdue,omitempty"`
+}
+
+// dataPath returns where a data file lives: the TODO_DIR directory when it
+// is set, otherwise the current directory
+func dataPath(name string) string {
+	if dir := os.Getenv("TODO_DIR"); dir != "" {
+		return filepath.Join(dir, name)
+	}
+	return name
+}
+
+// config is the active configuration
+var config Config
+
+// loadConfig reads settings from config.json file
+func loadConfig() (Config, error) {
+	var cfg Config
+	file, err := os.ReadFile(dataPath("config.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	return parseConfig(file)
+}
+
+// parseConfig reads and checks settings in the config.json format
+func parseConfig(file []byte) (Config, error) {
+	var cfg Config
+	// Drop the comment lines written by "todo init"
+	var lines []string
+	for _, line := range strings.Split(string(file), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "//") {
+			lines = append(lines, line)
+		}
+	}
+	if err := json.Unmarshal([]byte(strings.Join(lines, "\n")), &cfg); err != nil {
+		return cfg, err
+	}
+	if _, err := parseClock(cfg.DayEnd); err != nil {
+		return cfg, fmt.Errorf("day_end: %v", err)
+	}
+	if _, err := parseClock(cfg.DeadlineTime); err != nil {
+		return cfg, fmt.Errorf("deadline_time: %v", err)
+	}
+	if cfg.RemindBefore != "" {
+		if _, err := time.ParseDuration(cfg.RemindBefore); err != nil {
+			return cfg, fmt.Errorf("remind_before: %v", err)
+		}
+	}
+	if _, err := parseClock(cfg.QuietHours.From); err != nil {
+		return cfg, fmt.Errorf("quiet_hours.from: %v", err)
+	}
+	if _, err := parseClock(cfg.QuietHours.To); err != nil {
+		return cfg, fmt.Errorf("quiet_hours.to: %v", err)
+	}
+	for _, day := range cfg.QuietHours.Days {
+		if _, err := parseWeekday(day); err != nil {
+			return cfg, fmt.Errorf("quiet_hours.days: %v", err)
+		}
+	}
+	for _, clock := range cfg.Digest.Times {
+		if _, err := parseClock(clock); err != nil {
+			return cfg, fmt.Errorf("digest.times: %v", err)
+		}
+	}
+	for _, level := range cfg.Digest.Priorities {
+		if _, err := parsePriority(level); err != nil {
+			return cfg, fmt.Errorf("digest.priorities: %v", err)
+		}
+	}
+	if err := checkWeekStart(cfg.WeekStart); err != nil {
+		return cfg, fmt.Errorf("week_start: %v", err)
+	}
+	for level, chain := range cfg.Escalation {
+		if _, err := parsePriority(level); err != nil {
+			return cfg, fmt.Errorf("escalation: %v", err)
+		}
+		for _, lead := range chain.Before {
+			if _, err := parseLead(lead); err != nil {
+				return cfg, fmt.Errorf("escalation.%s.before: %v", level, err)
+			}
+		}
+		if chain.Overdue != "" {
+			if every, err := parseLead(chain.Overdue); err != nil || every <= 0 {
+				return cfg, fmt.Errorf("escalation.%s.overdue: expected a positive duration such as 1h, got %q", level, chain.Overdue)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// checkWeekStart accepts the values allowed for week_start
+func checkWeekStart(day string) error {
+	switch strings.ToLower(day) {
+	case "", "monday", "sunday":
+		return nil
+	}
+	return fmt.Errorf("expected monday or sunday, got %q", day)
+}
+
+// parseClock converts "HH:MM" into an offset from midnight; "" is zero
+func parseClock(clock string) (time.Duration, error) {
+	if clock == "" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// dateOf returns midnight of t's calendar date in the local time zone
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// deadlineOn returns the deadline for t's calendar date. Deadlines are
+// dates without a time zone and are kept as midnight UTC, which is how
+// "YYYY-MM-DD" parses, so they compare equal and read back the same from
+// JSON whatever the local zone is.
+func deadlineOn(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// logicalDate returns the date a moment belongs to, taking into account
+// that the day may end after midnight
+func logicalDate(t time.Time) time.Time {
+	dayEnd, _ := parseClock(config.DayEnd)
+	return dateOf(t.Add(-dayEnd))
+}
+
+// dayStart returns the moment the given date begins
+func dayStart(date time.Time) time.Time {
+	dayEnd, _ := parseClock(config.DayEnd)
+	return dateOf(date).Add(dayEnd)
+}
+
+// dueAt returns the moment a date-only deadline falls due
+func dueAt(deadline time.Time) time.Time {
+	date := dateOf(deadline)
+	dayEnd, _ := parseClock(config.DayEnd)
+	if config.DeadlineTime == "" {
+		return date.AddDate(0, 0, 1).Add(dayEnd)
+	}
+	clock, _ := parseClock(config.DeadlineTime)
+	if clock < dayEnd {
+		// Small hours still belong to the previous day
+		clock += 24 * time.Hour
+	}
+	return date.Add(clock)
+}
+
+// isOverdue reports whether an unfinished task's deadline has passed
+func isOverdue(task Task, now time.Time) bool {
+	return !task.Done && !task.Deadline.IsZero() && now.After(dueAt(task.Deadline))
+}
+
+// isDueToday reports whether a task's deadline falls on the current day
+func isDueToday(task Task, now time.Time) bool {
+	return !task.Deadline.IsZero() && dateOf(task.Deadline).Equal(logicalDate(now))
+}
+
+// sundayRegions are the locale territories whose weeks start on Sunday
+var sundayRegions = []string{"US", "CA", "MX", "BR", "JP", "KR", "TW", "HK", "IL", "PH", "ZA", "IN"}
+
+// firstWeekday returns the configured first day of the week, falling back
+// to the territory of the LC_ALL, LC_TIME or LANG locale
+func firstWeekday() time.Weekday {
+	switch strings.ToLower(config.WeekStart) {
+	case "monday":
+		return time.Monday
+	case "sunday":
+		return time.Sunday
+	}
+	for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		locale := os.Getenv(name)
+		if locale == "" {
+			continue
+		}
+		// e.g. en_US.UTF-8
+		locale = strings.SplitN(locale, ".", 2)[0]
+		if i := strings.IndexAny(locale, "_-"); i >= 0 {
+			for _, region := range sundayRegions {
+				if strings.EqualFold(locale[i+1:], region) {
+					return time.Sunday
+				}
+			}
+		}
+		break
+	}
+	return time.Monday
+}
+
+// weekStart returns the first day of the week containing date
+func weekStart(date time.Time) time.Time {
+	date = dateOf(date)
+	return date.AddDate(0, 0, -((int(date.Weekday()) - int(firstWeekday()) + 7) % 7))
+}
+
+// parseWeekday reads a weekday name such as "saturday" or "sat"
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(name)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || (len(name) >= 3 && strings.HasPrefix(full, name)) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
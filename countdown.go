@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// showCountdown adds a countdown column to task lists
+var showCountdown bool
+
+// parseColumns reads a comma-separated list of optional columns. Only
+// "countdown" exists so far.
+func parseColumns(columns []string) error {
+	for _, column := range columns {
+		for _, name := range strings.Split(column, ",") {
+			switch strings.TrimSpace(name) {
+			case "countdown":
+				showCountdown = true
+			case "":
+			default:
+				return fmt.Errorf("unknown column %q", name)
+			}
+		}
+	}
+	return nil
+}
+
+// countdown describes the time left until a task is due, such as
+// "3d 4h left" or "2d overdue". It is empty for done or undated tasks.
+func countdown(task Task, now time.Time) string {
+	if task.Done || task.Deadline.IsZero() {
+		return ""
+	}
+	left := dueAt(task.Deadline).Sub(now)
+	suffix := "left"
+	if left < 0 {
+		left, suffix = -left, "overdue"
+	}
+	days := int(left / (24 * time.Hour))
+	hours := int(left % (24 * time.Hour) / time.Hour)
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %s", days, hours, suffix)
+	}
+	return fmt.Sprintf("%dh %dm %s", hours, int(left%time.Hour/time.Minute), suffix)
+}
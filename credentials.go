@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// keychainService is the service name credentials are filed under
+const keychainService = "todo"
+
+// errNoCredential means the keychain has nothing stored for an account
+var errNoCredential = errors.New("no credential stored")
+
+// keychain stores secrets with the operating system's credential store
+type keychain interface {
+	get(account string) (string, error)
+	set(account, secret string) error
+	remove(account string) error
+}
+
+// runSecret runs a credential tool, feeding it input on stdin, and returns
+// its trimmed output
+func runSecret(input string, name string, args ...string) (string, error) {
+	var out, errOut bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(errOut.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s: %v", name, err)
+	}
+	return strings.TrimRight(out.String(), "\n"), nil
+}
+
+// macKeychain uses the macOS login keychain through the security tool
+type macKeychain struct{}
+
+func (macKeychain) get(account string) (string, error) {
+	secret, err := runSecret("", "security", "find-generic-password", "-s", keychainService, "-a", account, "-w")
+	if err != nil {
+		return "", errNoCredential
+	}
+	return secret, nil
+}
+
+// set feeds the command to security's interactive mode on stdin, so the
+// secret never appears in the process list; -X takes it hex encoded,
+// which needs no quoting. Interactive mode does not report failures in
+// its exit status, so the item is read back.
+func (k macKeychain) set(account, secret string) error {
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace
+	command := fmt.Sprintf("add-generic-password -U -s \"%s\" -a \"%s\" -X %s\n",
+		quote(keychainService), quote(account), hex.EncodeToString([]byte(secret)))
+	if _, err := runSecret(command, "security", "-i"); err != nil {
+		return err
+	}
+	if stored, err := k.get(account); err != nil || stored != secret {
+		return fmt.Errorf("security: could not store the %s credential", account)
+	}
+	return nil
+}
+
+func (macKeychain) remove(account string) error {
+	_, err := runSecret("", "security", "delete-generic-password", "-s", keychainService, "-a", account)
+	return err
+}
+
+// secretService uses the freedesktop Secret Service (GNOME Keyring,
+// KWallet) through secret-tool
+type secretService struct{}
+
+func (secretService) get(account string) (string, error) {
+	secret, err := runSecret("", "secret-tool", "lookup", "service", keychainService, "account", account)
+	if err != nil || secret == "" {
+		return "", errNoCredential
+	}
+	return secret, nil
+}
+
+func (secretService) set(account, secret string) error {
+	_, err := runSecret(secret, "secret-tool", "store", "--label", keychainService+" "+account, "service", keychainService, "account", account)
+	return err
+}
+
+func (secretService) remove(account string) error {
+	_, err := runSecret("", "secret-tool", "clear", "service", keychainService, "account", account)
+	return err
+}
+
+// kernelKeyring uses the Linux kernel's user keyring (@u) through keyctl,
+// for machines without a desktop keyring. Keys there last until reboot,
+// after which "auth login" has to be run again.
+type kernelKeyring struct{}
+
+func (kernelKeyring) key(account string) string {
+	return keychainService + ":" + account
+}
+
+func (k kernelKeyring) get(account string) (string, error) {
+	id, err := runSecret("", "keyctl", "search", "@u", "user", k.key(account))
+	if err != nil {
+		return "", errNoCredential
+	}
+	return runSecret("", "keyctl", "pipe", id)
+}
+
+func (k kernelKeyring) set(account, secret string) error {
+	_, err := runSecret(secret, "keyctl", "padd", "user", k.key(account), "@u")
+	return err
+}
+
+func (k kernelKeyring) remove(account string) error {
+	id, err := runSecret("", "keyctl", "search", "@u", "user", k.key(account))
+	if err != nil {
+		return errNoCredential
+	}
+	_, err = runSecret("", "keyctl", "unlink", id, "@u")
+	return err
+}
+
+// systemKeychain picks the credential store available on this machine, or
+// returns nil when there is none
+func systemKeychain() keychain {
+	has := func(tool string) bool {
+		_, err := exec.LookPath(tool)
+		return err == nil
+	}
+	switch {
+	case runtime.GOOS == "windows":
+		return windowsKeychain()
+	case runtime.GOOS == "darwin" && has("security"):
+		return macKeychain{}
+	case has("secret-tool"):
+		return secretService{}
+	case runtime.GOOS == "linux" && has("keyctl"):
+		return kernelKeyring{}
+	}
+	return nil
+}
+
+// providerToken returns a sync provider's token from the environment
+// variable env, or else from the keychain entry for account
+func providerToken(env, account string) string {
+	if token := os.Getenv(env); token != "" {
+		return token
+	}
+	if store := systemKeychain(); store != nil {
+		if token, err := store.get(account); err == nil {
+			return token
+		}
+	}
+	return ""
+}
+
+// oauthToken is an OAuth token kept between runs
+type oauthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+// loadOAuthToken reads a saved token from the keychain entry for account,
+// or from file, where older versions kept it, until saveOAuthToken moves
+// it into the keychain
+func loadOAuthToken(account, file string) oauthToken {
+	var token oauthToken
+	if store := systemKeychain(); store != nil {
+		if secret, err := store.get(account); err == nil {
+			json.Unmarshal([]byte(secret), &token)
+			return token
+		}
+	}
+	if data, err := os.ReadFile(dataPath(file)); err == nil {
+		json.Unmarshal(data, &token)
+	}
+	return token
+}
+
+// saveOAuthToken stores a token in the keychain entry for account and
+// removes any copy left in file. Tokens are never written in plain text,
+// so it fails when there is no keychain.
+func saveOAuthToken(account, file string, token oauthToken) error {
+	store := systemKeychain()
+	if store == nil {
+		return fmt.Errorf("no system keychain found (install secret-tool or keyctl) to store the sign-in token")
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := store.set(account, string(data)); err != nil {
+		return err
+	}
+	if err := os.Remove(dataPath(file)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// readSecret reads a line from the terminal without echoing it
+func readSecret(reader *bufio.Reader, prompt string) string {
+	fmt.Print(prompt)
+	hide := exec.Command("stty", "-echo")
+	hide.Stdin = os.Stdin
+	if hide.Run() == nil {
+		defer func() {
+			show := exec.Command("stty", "echo")
+			show.Stdin = os.Stdin
+			show.Run()
+			fmt.Println()
+		}()
+	}
+	line, _ := reader.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// credentialAccount names the keychain entry for a provider. Jira, GitLab
+// and CalDAV entries include the server so several instances can coexist.
+func credentialAccount(provider, instance string) (string, error) {
+	switch provider {
+	case "gitlab":
+		url := config.GitLab.URL
+		if url == "" {
+			url = "https://gitlab.com"
+		}
+		return "gitlab " + strings.TrimSuffix(url, "/"), nil
+	case "jira":
+		_, cfg, err := jiraInstance(instance)
+		if err != nil {
+			return "", err
+		}
+		return "jira " + strings.TrimSuffix(cfg.URL, "/"), nil
+	case "mstodo":
+		if config.Microsoft.ClientID == "" {
+			return "", fmt.Errorf("set microsoft.client_id in config")
+		}
+		return "mstodo " + config.Microsoft.ClientID, nil
+	case "gcal":
+		if config.Google.ClientID == "" {
+			return "", fmt.Errorf("set google.client_id in config")
+		}
+		return "gcal " + config.Google.ClientID, nil
+	case "caldav":
+		if config.CalDAV.URL == "" {
+			return "", fmt.Errorf("set caldav.url in config")
+		}
+		return "caldav " + strings.TrimSuffix(config.CalDAV.URL, "/") + "/", nil
+	}
+	return "", fmt.Errorf("unknown provider %q, use gitlab, jira, mstodo, gcal or caldav", provider)
+}
+
+// authCommand handles "auth login|logout <provider>"
+func authCommand(args []string, instance string, reader *bufio.Reader) error {
+	if len(args) < 2 || (args[0] != "login" && args[0] != "logout") {
+		return fmt.Errorf("use \"auth login <provider>\" or \"auth logout <provider>\"")
+	}
+	store := systemKeychain()
+	if store == nil {
+		return fmt.Errorf("no system keychain found (install secret-tool or keyctl); set the token environment variable instead")
+	}
+	account, err := credentialAccount(args[1], instance)
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "logout" {
+		if err := store.remove(account); err != nil && err != errNoCredential {
+			return err
+		}
+		fmt.Printf("%sRemoved the stored %s credential%s\n", yellow, args[1], reset)
+		return nil
+	}
+	if args[1] == "mstodo" {
+		// Signing in stores the OAuth token through saveToken
+		client := &msClient{cfg: config.Microsoft}
+		if client.cfg.Tenant == "" {
+			client.cfg.Tenant = "common"
+		}
+		if err := client.deviceLogin(); err != nil {
+			return err
+		}
+		if err := client.saveToken(); err != nil {
+			return err
+		}
+	} else if args[1] == "gcal" {
+		client := &gcalClient{cfg: config.Google}
+		if err := client.browserLogin(); err != nil {
+			return err
+		}
+		if err := client.saveToken(); err != nil {
+			return err
+		}
+	} else {
+		kind := "API token"
+		if args[1] == "caldav" {
+			kind = "password or app password"
+		}
+		token := readSecret(reader, "Paste the "+args[1]+" "+kind+": ")
+		if token == "" {
+			return fmt.Errorf("no token given")
+		}
+		if err := store.set(account, token); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%sStored the %s credential in the system keychain%s\n", green, args[1], reset)
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// windowsKeychain has no Credential Manager to offer off Windows
+func windowsKeychain() keychain {
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseOffset reads a relative offset such as "3d", "+2w" or "-1m"
+func parseOffset(spec string) (years, months, days int, err error) {
+	s := strings.TrimPrefix(spec, "+")
+	if len(s) < 2 {
+		return 0, 0, 0, fmt.Errorf("invalid offset %q", spec)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid offset %q", spec)
+	}
+	switch s[len(s)-1] {
+	case 'd':
+		return 0, 0, n, nil
+	case 'w':
+		return 0, 0, 7 * n, nil
+	case 'm':
+		return 0, n, 0, nil
+	case 'y':
+		return n, 0, 0, nil
+	}
+	return 0, 0, 0, fmt.Errorf("invalid offset %q, use d, w, m or y", spec)
+}
+
+// parseDateSpec understands "YYYY-MM-DD", "today", "tomorrow",
+// "next-<weekday>" and relative offsets like "+3d". It returns a function
+// giving the new deadline for a task's current one: offsets count from the
+// current deadline, or from today when there is none or it has passed.
+func parseDateSpec(spec string, now time.Time) (func(time.Time) time.Time, error) {
+	today := logicalDate(now)
+	fixed := func(date time.Time) func(time.Time) time.Time {
+		return func(time.Time) time.Time { return deadlineOn(date) }
+	}
+
+	switch spec = strings.ToLower(spec); {
+	case spec == "today":
+		return fixed(today), nil
+	case spec == "tomorrow":
+		return fixed(today.AddDate(0, 0, 1)), nil
+	case strings.HasPrefix(spec, "next-"):
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.TrimPrefix(spec, "next-") == strings.ToLower(d.String()) {
+				days := (int(d)-int(today.Weekday())+6)%7 + 1
+				return fixed(today.AddDate(0, 0, days)), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown weekday in %q", spec)
+	case strings.HasPrefix(spec, "+") || strings.HasPrefix(spec, "-"):
+		years, months, days, err := parseOffset(spec)
+		if err != nil {
+			return nil, err
+		}
+		return func(old time.Time) time.Time {
+			if old.IsZero() || dateOf(old).Before(today) {
+				old = today
+			}
+			return deadlineOn(dateOf(old).AddDate(years, months, days))
+		}, nil
+	}
+
+	date, err := time.Parse("2006-01-02", spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", spec)
+	}
+	return fixed(date), nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// batches reports whether a task's reminders go into the digest
+func (d DigestConfig) batches(task Task) bool {
+	if len(d.Times) == 0 {
+		return false
+	}
+	if len(d.Priorities) == 0 {
+		return true
+	}
+	for _, level := range d.Priorities {
+		if p, err := parsePriority(level); err == nil && p == task.Priority {
+			return true
+		}
+	}
+	return false
+}
+
+// digestTimes returns the digest moments on the days around now, in order
+func digestTimes(times []string, now time.Time) []time.Time {
+	var moments []time.Time
+	for offset := -1; offset <= 1; offset++ {
+		day := dateOf(now).AddDate(0, 0, offset)
+		for _, clock := range times {
+			at, _ := parseClock(clock)
+			moments = append(moments, day.Add(at))
+		}
+	}
+	sort.Slice(moments, func(i, j int) bool { return moments[i].Before(moments[j]) })
+	return moments
+}
+
+// latestDigest returns the last digest moment at or before now, or the zero
+// time when no digest times are configured
+func latestDigest(times []string, now time.Time) time.Time {
+	var latest time.Time
+	for _, at := range digestTimes(times, now) {
+		if !at.After(now) {
+			latest = at
+		}
+	}
+	return latest
+}
+
+// nextDigest returns the first digest moment after now
+func nextDigest(times []string, now time.Time) time.Time {
+	for _, at := range digestTimes(times, now) {
+		if at.After(now) {
+			return at
+		}
+	}
+	return now
+}
+
+// digest lists the batched tasks that are overdue or will come due within
+// lead of the next digest, so nothing slips between two digests
+func digest(tasks []Task, lead time.Duration, now time.Time) (string, string) {
+	until := nextDigest(config.Digest.Times, now).Add(lead)
+	sorted, _ := sortTasks(tasks, "deadline", false)
+	var lines []string
+	for _, task := range sorted {
+		if task.Done || task.Deadline.IsZero() || task.paused(now) || !config.Digest.batches(task) || dueAt(task.Deadline).After(until) {
+			continue
+		}
+		when := "due " + task.Deadline.Format("Mon 2006-01-02")
+		if isOverdue(task, now) {
+			when = "overdue since " + task.Deadline.Format("2006-01-02")
+		}
+		lines = append(lines, fmt.Sprintf("#%d %s (%s)", task.ID, task.Title, when))
+	}
+	if len(lines) == 0 {
+		return "Nothing due before the next digest", ""
+	}
+	return fmt.Sprintf("%d tasks coming up", len(lines)), strings.Join(lines, "\n")
+}
+
+// sendDigest shows a digest on the desktop and mails it when an address
+// is configured
+func sendDigest(title, body string) error {
+	fmt.Printf("%s %s\n", time.Now().Format("15:04"), title)
+	if body != "" {
+		fmt.Println(body)
+	}
+	alert("todo: "+title, body)
+	if config.Digest.Email == "" {
+		return nil
+	}
+	mail := fmt.Sprintf("To: %s\nSubject: todo: %s\nContent-Type: text/plain; charset=utf-8\n\n%s\n", config.Digest.Email, title, body)
+	cmd := exec.Command("sendmail", "-t")
+	cmd.Stdin = strings.NewReader(mail)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sendmail: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// daysBetween counts whole calendar days from one date to another
+func daysBetween(from, to time.Time) int {
+	return int(dateOf(to).Sub(dateOf(from)).Hours()+12) / 24
+}
+
+// dueWithin returns the unfinished tasks that fall due in the next days
+// days, including today, ordered by deadline
+func dueWithin(tasks []Task, days int, now time.Time) []Task {
+	last := logicalDate(now).AddDate(0, 0, days)
+	var due []Task
+	for _, task := range tasks {
+		if task.Done || task.Deadline.IsZero() || isOverdue(task, now) || dateOf(task.Deadline).After(last) {
+			continue
+		}
+		due = append(due, task)
+	}
+	due, _ = sortTasks(due, "deadline", false)
+	return due
+}
+
+// overdueTasks returns the unfinished tasks whose deadline has passed,
+// oldest first
+func overdueTasks(tasks []Task, now time.Time) []Task {
+	var late []Task
+	for _, task := range tasks {
+		if isOverdue(task, now) {
+			late = append(late, task)
+		}
+	}
+	late, _ = sortTasks(late, "deadline", false)
+	return late
+}
+
+// printDue lists tasks with how many days remain until each deadline
+func printDue(tasks []Task, now time.Time) {
+	for _, task := range tasks {
+		when := "today"
+		if n := daysBetween(logicalDate(now), task.Deadline); n == 1 {
+			when = "tomorrow"
+		} else if n > 1 {
+			when = fmt.Sprintf("in %d days", n)
+		}
+		date := "(" + task.Deadline.Format("2006-01-02") + ", " + when + ")"
+		if isDueToday(task, now) {
+			date = yellow + date + reset
+		}
+		prefix := fmt.Sprintf("#%d: %s", task.ID, priorityLabel(task.Priority))
+		fmt.Printf("%s%s %s\n", prefix, fitTitle(task.Title, visibleLen(prefix+date)+1), date)
+	}
+}
+
+// printOverdue lists overdue tasks with how many days late each one is
+func printOverdue(tasks []Task, now time.Time) {
+	for _, task := range tasks {
+		late := daysBetween(task.Deadline, logicalDate(now))
+		unit := "days"
+		if late == 1 {
+			unit = "day"
+		}
+		prefix := fmt.Sprintf("#%d: %s", task.ID, priorityLabel(task.Priority))
+		date := fmt.Sprintf("(%s, %d %s late)", task.Deadline.Format("2006-01-02"), late, unit)
+		fmt.Printf("%s%s %s%s%s\n", prefix, fitTitle(task.Title, visibleLen(prefix+date)+1), red, date, reset)
+	}
+}
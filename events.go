@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// eventPoll is how often "events --follow" checks the journal for changes
+const eventPoll = 500 * time.Millisecond
+
+// printEvents writes the operations after ID since as JSON lines and
+// returns the last ID written
+func printEvents(ops []Operation, since int) (int, error) {
+	for _, op := range ops {
+		if op.ID <= since {
+			continue
+		}
+		line, err := json.Marshal(op)
+		if err != nil {
+			return since, err
+		}
+		fmt.Println(string(line))
+		since = op.ID
+	}
+	return since, nil
+}
+
+// followEvents prints journal operations after ID since as they are
+// recorded, until interrupted. Every command that changes tasks, from
+// the CLI, the RPC socket or the listener, is journaled, so the journal
+// doubles as the change feed.
+func followEvents(since int) error {
+	var seen time.Time
+	var size int64
+	for {
+		info, err := os.Stat(dataPath("journal.txt"))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil && (!info.ModTime().Equal(seen) || info.Size() != size) {
+			seen, size = info.ModTime(), info.Size()
+			ops, err := loadJournal()
+			if err != nil {
+				// Caught mid-write; read it again on the next poll
+				seen = time.Time{}
+			} else if since, err = printEvents(ops, since); err != nil {
+				return err
+			}
+		}
+		time.Sleep(eventPoll)
+	}
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// expr is a compiled --select or --where expression. Expressions read task
+// fields as .title, .id, .done, .deadline, .project, .priority, .tags,
+// .pinned, .notes and .parent, and support literals, comparisons, && || !,
+// + for numbers and strings, "x in .tags" and a few functions.
+type expr func(fields map[string]interface{}) (interface{}, error)
+
+// exprFuncs are the functions available in expressions
+var exprFuncs = map[string]func(args []interface{}) (interface{}, error){
+	"upper": func(args []interface{}) (interface{}, error) {
+		s, err := stringArg("upper", args)
+		return strings.ToUpper(s), err
+	},
+	"lower": func(args []interface{}) (interface{}, error) {
+		s, err := stringArg("lower", args)
+		return strings.ToLower(s), err
+	},
+	"trim": func(args []interface{}) (interface{}, error) {
+		s, err := stringArg("trim", args)
+		return strings.TrimSpace(s), err
+	},
+	"len": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("len takes 1 argument")
+		}
+		switch v := args[0].(type) {
+		case string:
+			return float64(len([]rune(v))), nil
+		case []string:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("len needs a string or list")
+	},
+	"contains": func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("contains takes 2 arguments")
+		}
+		s, ok1 := args[0].(string)
+		sub, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("contains needs strings")
+		}
+		return strings.Contains(strings.ToLower(s), strings.ToLower(sub)), nil
+	},
+	"startsWith": func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("startsWith takes 2 arguments")
+		}
+		s, ok1 := args[0].(string)
+		prefix, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("startsWith needs strings")
+		}
+		return strings.HasPrefix(s, prefix), nil
+	},
+}
+
+// stringArg checks that a function got a single string
+func stringArg(name string, args []interface{}) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%s takes 1 argument", name)
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("%s needs a string", name)
+	}
+	return s, nil
+}
+
+// taskFields exposes a task to expressions
+func taskFields(task Task) map[string]interface{} {
+	deadline := ""
+	if !task.Deadline.IsZero() {
+		deadline = task.Deadline.Format("2006-01-02")
+	}
+	tags := task.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return map[string]interface{}{
+		"id":       float64(task.ID),
+		"title":    task.Title,
+		"done":     task.Done,
+		"deadline": deadline,
+		"project":  task.Project,
+		"priority": priorityNames[task.Priority],
+		"tags":     tags,
+		"pinned":   task.Pinned,
+		"notes":    task.Notes,
+		"parent":   float64(task.ParentID),
+	}
+}
+
+// formatValue renders an expression result for output
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []string:
+		return strings.Join(v, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// exprToken is one lexical token of an expression
+type exprToken struct {
+	kind string // "field", "ident", "string", "number", "op" or "end"
+	text string
+}
+
+// lexExpr splits an expression into tokens
+func lexExpr(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(src)
+	isName := func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '.' && i+1 < len(runes) && isName(runes[i+1]):
+			j := i + 1
+			for j < len(runes) && isName(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, exprToken{"field", string(runes[i+1 : j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && isName(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, exprToken{"ident", string(runes[i:j])})
+			i = j
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, exprToken{"number", string(runes[i:j])})
+			i = j
+		case r == '"' || r == '\'':
+			var text strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				text.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, exprToken{"string", text.String()})
+			i = j + 1
+		default:
+			op := string(r)
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = two
+				}
+			}
+			switch op {
+			case "==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "(", ")", ",":
+			default:
+				return nil, fmt.Errorf("unexpected %q", op)
+			}
+			tokens = append(tokens, exprToken{"op", op})
+			i += len(op)
+		}
+	}
+	return append(tokens, exprToken{kind: "end"}), nil
+}
+
+// exprParser builds an expr from tokens by precedence climbing
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// binaryLevels lists binary operators from loosest to tightest binding
+var binaryLevels = [][]string{{"||"}, {"&&"}, {"==", "!=", "<", "<=", ">", ">=", "in"}, {"+", "-"}}
+
+// compileExpr parses an expression
+func compileExpr(src string) (expr, error) {
+	tokens, err := lexExpr(src)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %v", src, err)
+	}
+	p := &exprParser{tokens: tokens}
+	e, err := p.binary(0)
+	if err == nil && p.peek().kind != "end" {
+		err = fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %v", src, err)
+	}
+	return e, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	token := p.tokens[p.pos]
+	if token.kind != "end" {
+		p.pos++
+	}
+	return token
+}
+
+// operator returns the binary operator at the current token for a level
+func (p *exprParser) operator(level int) string {
+	token := p.peek()
+	if token.kind != "op" && !(token.kind == "ident" && token.text == "in") {
+		return ""
+	}
+	for _, op := range binaryLevels[level] {
+		if token.text == op {
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *exprParser) binary(level int) (expr, error) {
+	if level == len(binaryLevels) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for op := p.operator(level); op != ""; op = p.operator(level) {
+		p.next()
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr(op, left, right)
+	}
+	return left, nil
+}
+
+func (p *exprParser) unary() (expr, error) {
+	token := p.peek()
+	if token.kind != "op" || (token.text != "!" && token.text != "-") {
+		return p.primary()
+	}
+	p.next()
+	operand, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return func(fields map[string]interface{}) (interface{}, error) {
+		v, err := operand(fields)
+		if err != nil {
+			return nil, err
+		}
+		if b, ok := v.(bool); ok && token.text == "!" {
+			return !b, nil
+		}
+		if n, ok := v.(float64); ok && token.text == "-" {
+			return -n, nil
+		}
+		return nil, fmt.Errorf("cannot apply %s to %s", token.text, formatValue(v))
+	}, nil
+}
+
+func (p *exprParser) primary() (expr, error) {
+	token := p.next()
+	switch token.kind {
+	case "field":
+		name := token.text
+		if _, ok := taskFields(Task{})[name]; !ok {
+			return nil, fmt.Errorf("unknown field .%s", name)
+		}
+		return func(fields map[string]interface{}) (interface{}, error) { return fields[name], nil }, nil
+	case "string":
+		return constExpr(token.text), nil
+	case "number":
+		n, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", token.text)
+		}
+		return constExpr(n), nil
+	case "ident":
+		switch token.text {
+		case "true", "false":
+			return constExpr(token.text == "true"), nil
+		}
+		fn, ok := exprFuncs[token.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q", token.text)
+		}
+		if p.next().text != "(" {
+			return nil, fmt.Errorf("%s needs arguments in parentheses", token.text)
+		}
+		var args []expr
+		for p.peek().text != ")" {
+			arg, err := p.binary(0)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().text != "," {
+				break
+			}
+			p.next()
+		}
+		if p.next().text != ")" {
+			return nil, fmt.Errorf("missing ) after arguments to %s", token.text)
+		}
+		return func(fields map[string]interface{}) (interface{}, error) {
+			values := make([]interface{}, len(args))
+			for i, arg := range args {
+				v, err := arg(fields)
+				if err != nil {
+					return nil, err
+				}
+				values[i] = v
+			}
+			return fn(values)
+		}, nil
+	case "op":
+		if token.text == "(" {
+			inner, err := p.binary(0)
+			if err != nil {
+				return nil, err
+			}
+			if p.next().text != ")" {
+				return nil, fmt.Errorf("missing )")
+			}
+			return inner, nil
+		}
+	case "end":
+		return nil, fmt.Errorf("unexpected end")
+	}
+	return nil, fmt.Errorf("unexpected %q", token.text)
+}
+
+// constExpr always yields value
+func constExpr(value interface{}) expr {
+	return func(map[string]interface{}) (interface{}, error) { return value, nil }
+}
+
+// binaryExpr combines two operands with an operator
+func binaryExpr(op string, left, right expr) expr {
+	return func(fields map[string]interface{}) (interface{}, error) {
+		a, err := left(fields)
+		if err != nil {
+			return nil, err
+		}
+		// && and || skip the right side when the left decides
+		if op == "&&" || op == "||" {
+			ab, ok := a.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%s needs true or false, got %s", op, formatValue(a))
+			}
+			if ab == (op == "||") {
+				return ab, nil
+			}
+			b, err := right(fields)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := b.(bool); !ok {
+				return nil, fmt.Errorf("%s needs true or false, got %s", op, formatValue(b))
+			}
+			return b, nil
+		}
+		b, err := right(fields)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "in":
+			list, ok := b.([]string)
+			if !ok {
+				return nil, fmt.Errorf("in needs a list on the right")
+			}
+			for _, item := range list {
+				if strings.EqualFold(item, formatValue(a)) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case "==":
+			return formatValue(a) == formatValue(b), nil
+		case "!=":
+			return formatValue(a) != formatValue(b), nil
+		}
+		an, aNum := a.(float64)
+		bn, bNum := b.(float64)
+		as, aStr := a.(string)
+		bs, bStr := b.(string)
+		switch {
+		case aNum && bNum:
+			switch op {
+			case "+":
+				return an + bn, nil
+			case "-":
+				return an - bn, nil
+			case "<":
+				return an < bn, nil
+			case "<=":
+				return an <= bn, nil
+			case ">":
+				return an > bn, nil
+			case ">=":
+				return an >= bn, nil
+			}
+		case aStr && bStr:
+			switch op {
+			case "+":
+				return as + bs, nil
+			case "<":
+				return as < bs, nil
+			case "<=":
+				return as <= bs, nil
+			case ">":
+				return as > bs, nil
+			case ">=":
+				return as >= bs, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot apply %s to %s and %s", op, formatValue(a), formatValue(b))
+	}
+}
+
+// whereTasks keeps the tasks for which cond is true
+func whereTasks(tasks []Task, cond expr) ([]Task, error) {
+	var matched []Task
+	for _, task := range tasks {
+		v, err := cond(taskFields(task))
+		if err != nil {
+			return nil, fmt.Errorf("task #%d: %v", task.ID, err)
+		}
+		keep, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("--where must give true or false, got %s", formatValue(v))
+		}
+		if keep {
+			matched = append(matched, task)
+		}
+	}
+	return matched, nil
+}
+
+// printSelected prints the selected values of each task, tab-separated
+func printSelected(tasks []Task, selects []expr) error {
+	for _, task := range tasks {
+		fields := taskFields(task)
+		values := make([]string, len(selects))
+		for i, sel := range selects {
+			v, err := sel(fields)
+			if err != nil {
+				return fmt.Errorf("task #%d: %v", task.ID, err)
+			}
+			values[i] = formatValue(v)
+		}
+		fmt.Println(strings.Join(values, "\t"))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompileExpr(t *testing.T) {
+	deadline, _ := time.Parse("2006-01-02", "2026-03-10")
+	task := Task{ID: 7, Title: "Write report", Deadline: deadline, Project: "work",
+		Priority: 3, Tags: []string{"Q1", "writing"}, ParentID: 2}
+	fields := taskFields(task)
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{".id", 7.0},
+		{".title", "Write report"},
+		{".deadline", "2026-03-10"},
+		{".priority", "high"},
+		{".parent", 2.0},
+		{".done", false},
+		{"1 + 2 - 4", -1.0},
+		{"-.id", -7.0},
+		{"1.5 + 1", 2.5},
+		{`.project + "/" + .title`, "work/Write report"},
+		{`'it\'s'`, "it's"},
+		{".id == 7", true},
+		{".id != 7", false},
+		{".id > 3 && .id <= 7", true},
+		{".id < 3 || .project == \"work\"", true},
+		{"!.done", true},
+		{"!(.id == 7)", false},
+		{`.deadline < "2026-04-01"`, true},
+		{`"q1" in .tags`, true},
+		{`"home" in .tags`, false},
+		{"1 == 1 && 2 == 2 || false", true},
+		{"false && 1", false},
+		{"true || 1", true},
+		{"upper(.project)", "WORK"},
+		{`lower("ABC")`, "abc"},
+		{`trim("  x ")`, "x"},
+		{"len(.title)", 12.0},
+		{"len(.tags)", 2.0},
+		{`contains(.title, "REPORT")`, true},
+		{`startsWith(.title, "write")`, false},
+		{`startsWith(lower(.title), "write")`, true},
+	}
+	for _, tt := range tests {
+		e, err := compileExpr(tt.src)
+		if err != nil {
+			t.Errorf("compileExpr(%q): %v", tt.src, err)
+			continue
+		}
+		got, err := e(fields)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestCompileExprErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		".id ==",
+		".nope",
+		"(.id",
+		".id )",
+		`"open`,
+		"1 @ 2",
+		"nope(1)",
+		"upper .title",
+		"upper(.title",
+		"1 2",
+	} {
+		if _, err := compileExpr(src); err == nil {
+			t.Errorf("compileExpr(%q) succeeded, want an error", src)
+		}
+	}
+}
+
+func TestExprRuntimeErrors(t *testing.T) {
+	fields := taskFields(Task{ID: 1, Title: "a"})
+	for _, src := range []string{
+		".title + 1",
+		"!.title",
+		"-.title",
+		".id && true",
+		"true && .id",
+		`"x" in .title`,
+		"upper(.id)",
+		"upper(.title, .title)",
+		"len(.done)",
+		"contains(.title)",
+		".done < true",
+	} {
+		e, err := compileExpr(src)
+		if err != nil {
+			t.Errorf("compileExpr(%q): %v", src, err)
+			continue
+		}
+		if v, err := e(fields); err == nil {
+			t.Errorf("%s = %v, want an error", src, v)
+		}
+	}
+}
+
+func TestWhereTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Title: "Pay rent", Tags: []string{"home"}},
+		{ID: 2, Title: "Write report", Project: "work", Done: true},
+		{ID: 3, Title: "Fix bug", Project: "work"},
+	}
+	cond, err := compileExpr(`.project == "work" && !.done`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matched, err := whereTasks(tasks, cond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matched) != 1 || matched[0].ID != 3 {
+		t.Errorf("whereTasks = %v, want only #3", matched)
+	}
+
+	cond, _ = compileExpr(".title")
+	if _, err := whereTasks(tasks, cond); err == nil {
+		t.Error("whereTasks with a string condition succeeded, want an error")
+	}
+}
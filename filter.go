@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// taskFilter is a parsed filter expression: whitespace-separated terms
+// that must all match
+type taskFilter []func(Task) bool
+
+// parseFilter understands the terms "pending", "done", "overdue",
+// "project:<name>", "tag:<tag>", "priority:<level>" and "due:<YYYY-MM-DD>"
+func parseFilter(expr string, now time.Time) (taskFilter, error) {
+	var filter taskFilter
+	for _, term := range strings.Fields(expr) {
+		key, value, hasValue := strings.Cut(term, ":")
+		switch {
+		case term == "pending":
+			filter = append(filter, func(t Task) bool { return !t.Done })
+		case term == "done":
+			filter = append(filter, func(t Task) bool { return t.Done })
+		case term == "overdue":
+			filter = append(filter, func(t Task) bool { return isOverdue(t, now) })
+		case hasValue && key == "project":
+			filter = append(filter, func(t Task) bool { return strings.EqualFold(t.Project, value) })
+		case hasValue && key == "tag":
+			filter = append(filter, func(t Task) bool { return hasTag(t, value) })
+		case hasValue && key == "priority":
+			priority, err := parsePriority(value)
+			if err != nil {
+				return nil, err
+			}
+			filter = append(filter, func(t Task) bool { return t.Priority == priority })
+		case hasValue && key == "due":
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid date in %q", term)
+			}
+			filter = append(filter, func(t Task) bool {
+				return !t.Deadline.IsZero() && dateOf(t.Deadline).Equal(dateOf(date))
+			})
+		default:
+			return nil, fmt.Errorf("unknown filter term %q", term)
+		}
+	}
+	return filter, nil
+}
+
+// match reports whether a task satisfies every term
+func (f taskFilter) match(task Task) bool {
+	for _, term := range f {
+		if !term(task) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the tasks matching the filter
+func (f taskFilter) apply(tasks []Task) []Task {
+	var matched []Task
+	for _, task := range tasks {
+		if f.match(task) {
+			matched = append(matched, task)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Focus is the set of tasks pinned for a deep-work block
+type Focus struct {
+	IDs   []int     `json:"ids"`
+	Since time.Time `json:"since"`
+}
+
+// loadFocus reads the active focus from focus.json file
+func loadFocus() (Focus, error) {
+	var focus Focus
+	file, err := os.ReadFile(dataPath("focus.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return focus, nil
+		}
+		return focus, err
+	}
+	err = json.Unmarshal(file, &focus)
+	return focus, err
+}
+
+// saveFocus writes the focus to focus.json file, removing it when empty
+func saveFocus(focus Focus) error {
+	if len(focus.IDs) == 0 {
+		if err := os.Remove(dataPath("focus.json")); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(focus, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dataPath("focus.json"), data, 0644)
+}
+
+// active reports whether a focus is in effect
+func (f Focus) active() bool {
+	return len(f.IDs) > 0
+}
+
+// filter keeps the focused tasks plus anything that became overdue after
+// the focus started
+func (f Focus) filter(tasks []Task, now time.Time) []Task {
+	focused := map[int]bool{}
+	for _, id := range f.IDs {
+		focused[id] = true
+	}
+
+	var shown []Task
+	for _, task := range tasks {
+		newlyOverdue := isOverdue(task, now) && dueAt(task.Deadline).After(f.Since)
+		if focused[task.ID] || newlyOverdue {
+			shown = append(shown, task)
+		}
+	}
+	return shown
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// GoogleConfig configures "sync gcal" against Google Calendar
+type GoogleConfig struct {
+	// ClientID and ClientSecret are those of an OAuth client of type
+	// "Desktop app" in a Google Cloud project with the Calendar API enabled
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	// Calendar is the calendar ID to put deadlines on, "primary" if empty
+	Calendar string `json:"calendar,omitempty"`
+}
+
+// gcalDate is an all-day date of an event
+type gcalDate struct {
+	Date string `json:"date"`
+}
+
+// gcalEvent is a calendar event as returned by the Calendar API
+type gcalEvent struct {
+	ID                 string   `json:"id,omitempty"`
+	Summary            string   `json:"summary"`
+	Description        string   `json:"description,omitempty"`
+	Start              gcalDate `json:"start"`
+	End                gcalDate `json:"end"`
+	Transparency       string   `json:"transparency,omitempty"`
+	ExtendedProperties struct {
+		Private map[string]string `json:"private,omitempty"`
+	} `json:"extendedProperties"`
+}
+
+const gcalAPIURL = "https://www.googleapis.com/calendar/v3"
+const gcalTokenURL = "https://oauth2.googleapis.com/token"
+const gcalScope = "https://www.googleapis.com/auth/calendar.events"
+
+// gcalClient talks to the Calendar API on behalf of the signed-in user
+type gcalClient struct {
+	cfg   GoogleConfig
+	token oauthToken
+}
+
+// newGCalClient loads the saved token, refreshing it or signing in through
+// the browser as needed
+func newGCalClient(cfg GoogleConfig) (*gcalClient, error) {
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("set google.client_id in config")
+	}
+	if cfg.Calendar == "" {
+		cfg.Calendar = "primary"
+	}
+	c := &gcalClient{cfg: cfg, token: loadOAuthToken("gcal "+cfg.ClientID, "gcal-token.json")}
+	switch {
+	case c.token.AccessToken != "" && time.Now().Before(c.token.Expiry.Add(-time.Minute)):
+		return c, nil
+	case c.token.RefreshToken != "":
+		if err := c.refresh(); err == nil {
+			return c, c.saveToken()
+		}
+	}
+	if err := c.browserLogin(); err != nil {
+		return nil, err
+	}
+	return c, c.saveToken()
+}
+
+// saveToken stores the token in the system keychain
+func (c *gcalClient) saveToken() error {
+	return saveOAuthToken("gcal "+c.cfg.ClientID, "gcal-token.json", c.token)
+}
+
+// refresh swaps the refresh token for a new access token. Google keeps the
+// refresh token the same, so it is not part of the reply.
+func (c *gcalClient) refresh() error {
+	var reply tokenReply
+	err := postForm(gcalTokenURL, url.Values{
+		"client_id":     {c.cfg.ClientID},
+		"client_secret": {c.cfg.ClientSecret},
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {c.token.RefreshToken},
+	}, &reply)
+	if err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s: %s", reply.Error, reply.Description)
+	}
+	refreshToken := c.token.RefreshToken
+	c.token = reply.toToken()
+	if c.token.RefreshToken == "" {
+		c.token.RefreshToken = refreshToken
+	}
+	return nil
+}
+
+// browserLogin signs in with Google's flow for desktop apps: the consent
+// page is opened in a browser, which then hands the code to a listener on
+// the loopback interface. PKCE ties the code to this run.
+func (c *gcalClient) browserLogin() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	redirect := "http://" + listener.Addr().String()
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	verifier := base64.RawURLEncoding.EncodeToString(random)
+	challenge := sha256.Sum256([]byte(verifier))
+	state := newUID()
+	link := "https://accounts.google.com/o/oauth2/v2/auth?" + url.Values{
+		"client_id":             {c.cfg.ClientID},
+		"redirect_uri":          {redirect},
+		"response_type":         {"code"},
+		"scope":                 {gcalScope},
+		"access_type":           {"offline"},
+		"prompt":                {"consent"},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}.Encode()
+
+	codes := make(chan string, 1)
+	failures := make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case query.Get("state") != state:
+			http.Error(w, "Unexpected sign-in reply", http.StatusBadRequest)
+			return
+		case query.Get("error") != "":
+			fmt.Fprintln(w, "Sign-in failed, you can close this tab.")
+			select {
+			case failures <- fmt.Errorf("sign-in: %s", query.Get("error")):
+			default:
+			}
+		default:
+			fmt.Fprintln(w, "Signed in to todo, you can close this tab.")
+			select {
+			case codes <- query.Get("code"):
+			default:
+			}
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	fmt.Printf("Open this link to let todo manage your calendar events:\n  %s\n", link)
+	openURL(link)
+
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-failures:
+		return err
+	case <-time.After(5 * time.Minute):
+		return fmt.Errorf("sign-in timed out")
+	}
+	var reply tokenReply
+	err = postForm(gcalTokenURL, url.Values{
+		"client_id":     {c.cfg.ClientID},
+		"client_secret": {c.cfg.ClientSecret},
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"code_verifier": {verifier},
+		"redirect_uri":  {redirect},
+	}, &reply)
+	if err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s: %s", reply.Error, reply.Description)
+	}
+	c.token = reply.toToken()
+	return nil
+}
+
+// do sends a Calendar API request for a path under the calendar
+func (c *gcalClient) do(method, path string, body, out interface{}) error {
+	req, err := http.NewRequest(method, gcalAPIURL+"/calendars/"+url.PathEscape(c.cfg.Calendar)+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token.AccessToken)
+	return doJSON(req, body, out)
+}
+
+// events returns the events created for tasks
+func (c *gcalClient) events() ([]gcalEvent, error) {
+	var all []gcalEvent
+	pageToken := ""
+	for {
+		query := url.Values{"privateExtendedProperty": {"source=todo"}, "maxResults": {"2500"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		var page struct {
+			Items         []gcalEvent `json:"items"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		if err := c.do("GET", "/events?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Items...)
+		if page.NextPageToken == "" {
+			return all, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// gcalEventFor renders the all-day event that marks a task's deadline
+func gcalEventFor(task Task) gcalEvent {
+	event := gcalEvent{
+		Summary:      task.Title,
+		Description:  task.Notes,
+		Start:        gcalDate{task.Deadline.Format("2006-01-02")},
+		End:          gcalDate{task.Deadline.AddDate(0, 0, 1).Format("2006-01-02")},
+		Transparency: "transparent",
+	}
+	event.ExtendedProperties.Private = map[string]string{"source": "todo", "task": task.UID}
+	return event
+}
+
+// syncGoogleCalendar puts the deadline of every unfinished task on Google
+// Calendar as an all-day event. Events follow their task's title, notes
+// and deadline, and are deleted once the task is done, loses its deadline
+// or is deleted. Events are found by the task UID stored in them, so
+// nothing needs to be kept locally; edits made to them in the calendar
+// are overwritten.
+func syncGoogleCalendar(tasks []Task, cfg GoogleConfig) ([]Task, error) {
+	client, err := newGCalClient(cfg)
+	if err != nil {
+		return tasks, err
+	}
+	events, err := client.events()
+	if err != nil {
+		return tasks, err
+	}
+	tasks = ensureUIDs(tasks)
+
+	var created, updated, removed int
+	byTask := map[string]gcalEvent{}
+	for _, event := range events {
+		uid := event.ExtendedProperties.Private["task"]
+		if _, dup := byTask[uid]; dup || uid == "" {
+			if err := client.do("DELETE", "/events/"+url.PathEscape(event.ID), nil, nil); err != nil {
+				return tasks, err
+			}
+			removed++
+			continue
+		}
+		byTask[uid] = event
+	}
+
+	for _, task := range tasks {
+		event, exists := byTask[task.UID]
+		delete(byTask, task.UID)
+		if task.Done || task.Deadline.IsZero() {
+			if exists {
+				if err := client.do("DELETE", "/events/"+url.PathEscape(event.ID), nil, nil); err != nil {
+					return tasks, err
+				}
+				removed++
+			}
+			continue
+		}
+		want := gcalEventFor(task)
+		switch {
+		case !exists:
+			if err := client.do("POST", "/events", want, nil); err != nil {
+				return tasks, err
+			}
+			created++
+		case event.Summary != want.Summary || event.Description != want.Description || event.Start != want.Start || event.End != want.End:
+			if err := client.do("PUT", "/events/"+url.PathEscape(event.ID), want, nil); err != nil {
+				return tasks, err
+			}
+			updated++
+		}
+	}
+
+	// Whatever is left belongs to deleted or archived tasks
+	for _, event := range byTask {
+		if err := client.do("DELETE", "/events/"+url.PathEscape(event.ID), nil, nil); err != nil {
+			return tasks, err
+		}
+		removed++
+	}
+	fmt.Printf("%sGoogle Calendar: %d created, %d updated, %d removed%s\n", green, created, updated, removed, reset)
+	return tasks, nil
+}
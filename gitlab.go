@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitLabConfig describes the GitLab server to sync with
+type GitLabConfig struct {
+	// URL defaults to https://gitlab.com
+	URL string `json:"url,omitempty"`
+	// TokenEnv names the environment variable holding a personal access
+	// token (GITLAB_TOKEN if empty)
+	TokenEnv string `json:"token_env,omitempty"`
+}
+
+// gitlabIssue is the part of an issue used for syncing
+type gitlabIssue struct {
+	ID         int    `json:"id"`
+	Title      string `json:"title"`
+	DueDate    string `json:"due_date"`
+	WebURL     string `json:"web_url"`
+	References struct {
+		Full string `json:"full"`
+	} `json:"references"`
+}
+
+// gitlabTodo is a pending item on the user's GitLab to-do list
+type gitlabTodo struct {
+	ID         int    `json:"id"`
+	ActionName string `json:"action_name"`
+	TargetType string `json:"target_type"`
+	TargetURL  string `json:"target_url"`
+	Target     struct {
+		ID    int    `json:"id"`
+		IID   int    `json:"iid"`
+		Title string `json:"title"`
+	} `json:"target"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+}
+
+// gitlabClient talks to the GitLab REST API
+type gitlabClient struct {
+	baseURL string
+	token   string
+}
+
+// newGitLabClient validates the GitLab settings
+func newGitLabClient(cfg GitLabConfig) (*gitlabClient, error) {
+	if cfg.URL == "" {
+		cfg.URL = "https://gitlab.com"
+	}
+	if cfg.TokenEnv == "" {
+		cfg.TokenEnv = "GITLAB_TOKEN"
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	token := providerToken(cfg.TokenEnv, "gitlab "+cfg.URL)
+	if token == "" {
+		return nil, fmt.Errorf("gitlab token not set, export %s or run \"auth login gitlab\"", cfg.TokenEnv)
+	}
+	return &gitlabClient{baseURL: cfg.URL + "/api/v4", token: token}, nil
+}
+
+// newRequest builds an authenticated request against the API
+func (c *gitlabClient) newRequest(method, path string) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	return req, nil
+}
+
+// assignedIssues returns the open issues assigned to the token's user
+func (c *gitlabClient) assignedIssues() ([]gitlabIssue, error) {
+	var issues []gitlabIssue
+	for page := 1; ; page++ {
+		req, err := c.newRequest("GET", "/issues?scope=assigned_to_me&state=opened&per_page=100&page="+strconv.Itoa(page))
+		if err != nil {
+			return nil, err
+		}
+		var batch []gitlabIssue
+		if err := doJSON(req, nil, &batch); err != nil {
+			return nil, err
+		}
+		issues = append(issues, batch...)
+		if len(batch) < 100 {
+			return issues, nil
+		}
+	}
+}
+
+// pendingTodos returns the user's pending GitLab to-do items
+func (c *gitlabClient) pendingTodos() ([]gitlabTodo, error) {
+	var todos []gitlabTodo
+	for page := 1; ; page++ {
+		req, err := c.newRequest("GET", "/todos?state=pending&per_page=100&page="+strconv.Itoa(page))
+		if err != nil {
+			return nil, err
+		}
+		var batch []gitlabTodo
+		if err := doJSON(req, nil, &batch); err != nil {
+			return nil, err
+		}
+		todos = append(todos, batch...)
+		if len(batch) < 100 {
+			return todos, nil
+		}
+	}
+}
+
+// resolveTodo marks a GitLab to-do item as done
+func (c *gitlabClient) resolveTodo(id int) error {
+	req, err := c.newRequest("POST", "/todos/"+strconv.Itoa(id)+"/mark_as_done")
+	if err != nil {
+		return err
+	}
+	return doJSON(req, nil, nil)
+}
+
+// syncGitLab imports assigned issues and merge request review requests,
+// and resolves the GitLab to-do items of tasks completed locally
+func syncGitLab(tasks []Task, cfg GitLabConfig) ([]Task, error) {
+	client, err := newGitLabClient(cfg)
+	if err != nil {
+		return tasks, err
+	}
+	issues, err := client.assignedIssues()
+	if err != nil {
+		return tasks, err
+	}
+	todos, err := client.pendingTodos()
+	if err != nil {
+		return tasks, err
+	}
+
+	var items []remoteItem
+	resolved := 0
+	for _, todo := range todos {
+		// Issue to-dos are resolved through the issue's own task
+		remote := Remote{Source: "gitlab", ID: "todo:" + strconv.Itoa(todo.ID), URL: todo.TargetURL}
+		if todo.TargetType == "Issue" {
+			remote.ID = "issue:" + strconv.Itoa(todo.Target.ID)
+		}
+		if task := findRemote(tasks, remote); task != nil && task.Done {
+			if err := client.resolveTodo(todo.ID); err != nil {
+				return tasks, err
+			}
+			resolved++
+			continue
+		}
+		if todo.TargetType == "MergeRequest" && todo.ActionName == "review_requested" {
+			items = append(items, remoteItem{
+				Remote: remote,
+				Title:  fmt.Sprintf("Review %s!%d: %s", todo.Project.PathWithNamespace, todo.Target.IID, todo.Target.Title),
+			})
+		}
+	}
+	for _, issue := range issues {
+		item := remoteItem{
+			Remote: Remote{Source: "gitlab", ID: "issue:" + strconv.Itoa(issue.ID), URL: issue.WebURL},
+			Title:  issue.References.Full + ": " + issue.Title,
+		}
+		if parsed, err := time.Parse("2006-01-02", issue.DueDate); err == nil {
+			item.Deadline = parsed
+		}
+		items = append(items, item)
+	}
+
+	var added, updated int
+	tasks, added, updated = applyRemoteItems(tasks, items)
+	fmt.Printf("%sGitLab: %d added, %d updated, %d to-dos resolved%s\n", green, added, updated, resolved, reset)
+	return tasks, nil
+}
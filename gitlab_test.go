@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestSyncGitLabReplay(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "test-token")
+	withConfig(t, Config{GitLab: GitLabConfig{URL: "https://gitlab.example.com/"}})
+	tasks := []Task{
+		{ID: 1, Title: "group/app#2: Old bug", Done: true, Remotes: []Remote{{Source: "gitlab", ID: "issue:103"}}},
+		{ID: 2, Title: "group/app#3: Fix crash", Remotes: []Remote{{Source: "gitlab", ID: "issue:101"}}},
+	}
+	tasks = replay(t, tasks, "gitlab", "--replay-http", "testdata/gitlab.jsonl")
+
+	if len(tasks) != 4 {
+		t.Fatalf("got %d tasks, want 4: %+v", len(tasks), tasks)
+	}
+	renamed := findTask(tasks, 2)
+	if renamed.Title != "group/app#3: Fix crash on start" || renamed.Deadline.Format("2006-01-02") != "2026-03-20" {
+		t.Errorf("linked issue not refreshed: %+v", *renamed)
+	}
+	if renamed.Remotes[0].URL != "https://gitlab.example.com/group/app/-/issues/3" {
+		t.Errorf("linked issue URL not filled in: %+v", renamed.Remotes)
+	}
+	review := findRemote(tasks, Remote{Source: "gitlab", ID: "todo:501"})
+	if review == nil || review.Title != "Review group/app!9: Add caching" {
+		t.Errorf("review request not imported: %+v", review)
+	}
+	issue := findRemote(tasks, Remote{Source: "gitlab", ID: "issue:104"})
+	if issue == nil || issue.Title != "group/app#4: Update docs" || !issue.Deadline.IsZero() {
+		t.Errorf("new issue not imported: %+v", issue)
+	}
+}
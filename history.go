@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// historyLimit is how many commands are kept in the history
+const historyLimit = 100
+
+// HistoryEntry is one successfully run command line
+type HistoryEntry struct {
+	Time time.Time `json:"time"`
+	Args []string  `json:"args"`
+}
+
+// loadHistory reads past commands from history.json file
+func loadHistory() ([]HistoryEntry, error) {
+	var history []HistoryEntry
+	file, err := os.ReadFile(dataPath("history.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(file, &history)
+	return history, err
+}
+
+// recordCommand appends a command line to history.json file
+func recordCommand(args []string) error {
+	history, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	history = append(history, HistoryEntry{Time: time.Now(), Args: args})
+	if len(history) > historyLimit {
+		history = history[len(history)-historyLimit:]
+	}
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dataPath("history.json"), data, 0644)
+}
+
+// quoteArgs renders arguments the way they would be typed in a shell
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'\\$`*?|&;<>()") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// printHistory shows the last limit commands, oldest first
+func printHistory(history []HistoryEntry, limit int) {
+	if len(history) == 0 {
+		fmt.Println(yellow + "No commands recorded" + reset)
+		return
+	}
+	start := 0
+	if len(history) > limit {
+		start = len(history) - limit
+	}
+	for i := start; i < len(history); i++ {
+		entry := history[i]
+		fmt.Printf("%4d  %s  todo %s\n", i+1, entry.Time.Format("2006-01-02 15:04"), quoteArgs(entry.Args))
+	}
+}
+
+// redoLast runs the most recent command again with extra arguments appended
+func redoLast(extra []string) error {
+	history, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		return fmt.Errorf("no commands recorded")
+	}
+	args := append(history[len(history)-1].Args, extra...)
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%stodo %s%s\n", yellow, quoteArgs(args), reset)
+	cmd := exec.Command(self, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
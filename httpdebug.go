@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// exchange is one recorded HTTP request and its response
+type exchange struct {
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeader  http.Header `json:"request_header,omitempty"`
+	RequestBody    string      `json:"request_body,omitempty"`
+	Status         int         `json:"status"`
+	ResponseHeader http.Header `json:"response_header,omitempty"`
+	ResponseBody   string      `json:"response_body,omitempty"`
+}
+
+// redacted replaces secrets in captures
+const redacted = "REDACTED"
+
+// secretName reports whether a header, parameter or JSON field holds a
+// credential
+func secretName(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "token") || strings.Contains(name, "secret") ||
+		strings.Contains(name, "password") || name == "authorization" ||
+		name == "cookie" || name == "set-cookie" || name == "code" || name == "device_code"
+}
+
+// sanitizeURL redacts secret query parameters
+func sanitizeURL(u *url.URL) string {
+	clean := *u
+	query := clean.Query()
+	for name := range query {
+		if secretName(name) {
+			query.Set(name, redacted)
+		}
+	}
+	clean.RawQuery = query.Encode()
+	clean.User = nil
+	return clean.String()
+}
+
+// sanitizeHeader copies a header with credentials redacted
+func sanitizeHeader(header http.Header) http.Header {
+	clean := http.Header{}
+	for name, values := range header {
+		if secretName(name) {
+			values = []string{redacted}
+		}
+		clean[name] = values
+	}
+	return clean
+}
+
+// sanitizeJSON redacts secret fields anywhere in a decoded JSON value
+func sanitizeJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if secretName(key) {
+				v[key] = redacted
+			} else {
+				v[key] = sanitizeJSON(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = sanitizeJSON(v[i])
+		}
+	}
+	return value
+}
+
+// sanitizeBody redacts secrets in a JSON or form encoded body
+func sanitizeBody(body []byte, contentType string) string {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			for name := range form {
+				if secretName(name) {
+					form.Set(name, redacted)
+				}
+			}
+			return form.Encode()
+		}
+	}
+	var value interface{}
+	if json.Unmarshal(body, &value) == nil {
+		if clean, err := json.Marshal(sanitizeJSON(value)); err == nil {
+			return string(clean)
+		}
+	}
+	return string(body)
+}
+
+// captureTransport records every exchange, sanitized, as a line of JSON
+type captureTransport struct {
+	next http.RoundTripper
+	out  io.Writer
+	mu   sync.Mutex
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	var record bytes.Buffer
+	encoder := json.NewEncoder(&record)
+	encoder.SetEscapeHTML(false)
+	err = encoder.Encode(exchange{
+		Method:         req.Method,
+		URL:            sanitizeURL(req.URL),
+		RequestHeader:  sanitizeHeader(req.Header),
+		RequestBody:    sanitizeBody(reqBody, req.Header.Get("Content-Type")),
+		Status:         resp.StatusCode,
+		ResponseHeader: sanitizeHeader(resp.Header),
+		ResponseBody:   sanitizeBody(respBody, resp.Header.Get("Content-Type")),
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.out.Write(record.Bytes()); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// replayTransport answers requests from a capture instead of the network.
+// Each recorded exchange is used once, in order, for a request with the
+// same method and sanitized URL.
+type replayTransport struct {
+	exchanges []exchange
+	used      []bool
+	mu        sync.Mutex
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	target := sanitizeURL(req.URL)
+	for i, ex := range t.exchanges {
+		if t.used[i] || ex.Method != req.Method || ex.URL != target {
+			continue
+		}
+		t.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", ex.Status, http.StatusText(ex.Status)),
+			StatusCode:    ex.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        ex.ResponseHeader,
+			Body:          io.NopCloser(strings.NewReader(ex.ResponseBody)),
+			ContentLength: int64(len(ex.ResponseBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded response for %s %s", req.Method, target)
+}
+
+// loadCapture reads the exchanges recorded in a capture file
+func loadCapture(path string) ([]exchange, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var exchanges []exchange
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var ex exchange
+		if err := json.Unmarshal(scanner.Bytes(), &ex); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		exchanges = append(exchanges, ex)
+	}
+	return exchanges, scanner.Err()
+}
+
+// setupHTTPDebug handles the --debug-http <file> and --replay-http <file>
+// options of sync, removing them from args. It returns the remaining
+// arguments and a function that finishes writing the capture.
+func setupHTTPDebug(args []string) ([]string, func() error, error) {
+	done := func() error { return nil }
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		option := args[i]
+		if option != "--debug-http" && option != "--replay-http" {
+			rest = append(rest, option)
+			continue
+		}
+		if i+1 >= len(args) {
+			return nil, done, fmt.Errorf("%s requires a file", option)
+		}
+		i++
+		if option == "--replay-http" {
+			exchanges, err := loadCapture(args[i])
+			if err != nil {
+				return nil, done, err
+			}
+			httpClient.Transport = &replayTransport{exchanges: exchanges, used: make([]bool, len(exchanges))}
+			continue
+		}
+		file, err := os.OpenFile(args[i], os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, done, err
+		}
+		next := httpClient.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		httpClient.Transport = &captureTransport{next: next, out: file}
+		done = file.Close
+	}
+	return rest, done, nil
+}
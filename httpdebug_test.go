@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCaptureAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc123")
+		io.WriteString(w, `{"access_token":"tok-secret","items":[{"title":"Pay rent","password":"hunter2"}]}`)
+	}))
+	defer server.Close()
+	saved := httpClient.Transport
+	defer func() { httpClient.Transport = saved }()
+	capture := filepath.Join(t.TempDir(), "capture.jsonl")
+
+	// Record
+	_, finish, err := setupHTTPDebug([]string{"--debug-http", capture})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", server.URL+"/tasks?page=2&token=tok-secret", strings.NewReader("code=abc123&title=rent"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer tok-secret")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err := finish(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(live), "tok-secret") {
+		t.Error("capturing changed the response the caller sees")
+	}
+	data, err := os.ReadFile(capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"tok-secret", "abc123", "hunter2"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("capture contains %q:\n%s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "Pay rent") || !strings.Contains(string(data), "title=rent") {
+		t.Errorf("capture lost non-secret data:\n%s", data)
+	}
+
+	// Replay without the server
+	server.Close()
+	httpClient.Transport = saved
+	if _, _, err := setupHTTPDebug([]string{"--replay-http", capture}); err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest("POST", server.URL+"/tasks?token=other&page=2", nil)
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("replaying the recorded request: %v", err)
+	}
+	replayed, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || !strings.Contains(string(replayed), `"title":"Pay rent"`) {
+		t.Errorf("replayed %d %s", resp.StatusCode, replayed)
+	}
+	// Each exchange is answered once
+	req, _ = http.NewRequest("POST", server.URL+"/tasks?page=2&token=x", nil)
+	if _, err := httpClient.Do(req); err == nil {
+		t.Error("a recorded exchange was replayed twice")
+	}
+}
+
+func TestSetupHTTPDebugErrors(t *testing.T) {
+	saved := httpClient.Transport
+	defer func() { httpClient.Transport = saved }()
+	missing := filepath.Join(t.TempDir(), "missing.jsonl")
+	for _, args := range [][]string{
+		{"gitlab", "--debug-http"},
+		{"gitlab", "--replay-http"},
+		{"gitlab", "--replay-http", missing},
+	} {
+		if _, _, err := setupHTTPDebug(args); err == nil {
+			t.Errorf("setupHTTPDebug(%v) succeeded, want an error", args)
+		}
+	}
+	rest, _, err := setupHTTPDebug([]string{"jira", "--jql", "x"})
+	if err != nil || strings.Join(rest, " ") != "jira --jql x" {
+		t.Errorf("setupHTTPDebug without its options = %v, %v", rest, err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// icsEscape escapes text for an iCalendar property value
+func icsEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// icsFold splits a content line into lines of at most 75 octets, as
+// RFC 5545 requires, without breaking UTF-8 sequences
+func icsFold(line string) string {
+	var out strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			out.WriteString("\r\n ")
+			width = 1
+		}
+		out.WriteRune(r)
+		width += size
+	}
+	return out.String() + "\r\n"
+}
+
+// icsPriority maps priorities onto the iCalendar 1 (high) to 9 (low) scale
+var icsPriority = map[int]int{priorityLow: 9, priorityMedium: 5, priorityHigh: 1}
+
+// exportICS renders the tasks with deadlines as an iCalendar file that
+// calendar apps can import or subscribe to. Each becomes an all-day event,
+// or a VTODO when asTodo is set, for apps with task lists.
+func exportICS(tasks []Task, asTodo bool, now time.Time) []byte {
+	var out strings.Builder
+	line := func(format string, args ...interface{}) {
+		out.WriteString(icsFold(fmt.Sprintf(format, args...)))
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//todo//CLI To-Do List//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:To-do deadlines")
+	for _, task := range tasks {
+		if task.Deadline.IsZero() {
+			continue
+		}
+		uid := task.UID
+		if uid == "" {
+			uid = fmt.Sprintf("task-%d", task.ID)
+		}
+		due := task.Deadline.Format("20060102")
+		component := "VEVENT"
+		if asTodo {
+			component = "VTODO"
+		}
+		line("BEGIN:%s", component)
+		line("UID:%s@todo", uid)
+		line("DTSTAMP:%s", now.UTC().Format("20060102T150405Z"))
+		line("SUMMARY:%s", icsEscape(task.Title))
+		if asTodo {
+			line("DUE;VALUE=DATE:%s", due)
+			if task.Done {
+				line("STATUS:COMPLETED")
+				if !task.CompletedAt.IsZero() {
+					line("COMPLETED:%s", task.CompletedAt.UTC().Format("20060102T150405Z"))
+				}
+			} else {
+				line("STATUS:NEEDS-ACTION")
+			}
+		} else {
+			line("DTSTART;VALUE=DATE:%s", due)
+			line("DTEND;VALUE=DATE:%s", task.Deadline.AddDate(0, 0, 1).Format("20060102"))
+			line("TRANSP:TRANSPARENT")
+			if task.Done {
+				line("STATUS:CANCELLED")
+			}
+		}
+		// Only the open occurrence carries the rule; finished ones are
+		// its history
+		if rule := rruleFor(task.Repeat); rule != "" && !task.Done {
+			line("RRULE:%s", rule)
+		}
+		if p, ok := icsPriority[task.Priority]; ok {
+			line("PRIORITY:%d", p)
+		}
+		if len(task.Tags) > 0 {
+			escaped := make([]string, len(task.Tags))
+			for i, tag := range task.Tags {
+				escaped[i] = icsEscape(tag)
+			}
+			line("CATEGORIES:%s", strings.Join(escaped, ","))
+		}
+		if task.Notes != "" {
+			line("DESCRIPTION:%s", icsEscape(task.Notes))
+		}
+		if link := taskLink(task); link != "" {
+			line("URL:%s", link)
+		}
+		line("END:%s", component)
+	}
+	line("END:VCALENDAR")
+	return []byte(out.String())
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestICSFold(t *testing.T) {
+	for _, line := range []string{
+		"SUMMARY:short",
+		"SUMMARY:" + strings.Repeat("a", 200),
+		"SUMMARY:" + strings.Repeat("ü", 100),
+		"SUMMARY:" + strings.Repeat("日本語", 30),
+	} {
+		folded := icsFold(line)
+		if !strings.HasSuffix(folded, "\r\n") {
+			t.Errorf("icsFold(%q) does not end in CRLF", line)
+		}
+		for _, part := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+			if len(part) > 75 {
+				t.Errorf("icsFold left a %d octet line", len(part))
+			}
+			if !utf8.ValidString(part) {
+				t.Errorf("icsFold split a UTF-8 sequence: %q", part)
+			}
+		}
+		if got := icsLines(folded); len(got) != 1 || got[0] != line {
+			t.Errorf("unfolding icsFold(%q) gave %q", line, got)
+		}
+	}
+}
+
+func TestICSEscape(t *testing.T) {
+	text := "a, b; c\\d\nnext line"
+	escaped := icsEscape(text)
+	if want := `a\, b\; c\\d\nnext line`; escaped != want {
+		t.Errorf("icsEscape = %q, want %q", escaped, want)
+	}
+	if got := icsUnescape(escaped); got != text {
+		t.Errorf("icsUnescape(icsEscape(%q)) = %q", text, got)
+	}
+}
+
+func TestExportICS(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	deadline, _ := time.Parse("2006-01-02", "2026-03-10")
+	tasks := []Task{
+		{ID: 1, Title: "Pay rent, on time", Deadline: deadline, UID: "abc", Priority: priorityHigh,
+			Tags: []string{"home"}, Repeat: "monthly"},
+		{ID: 2, Title: "No deadline"},
+		{ID: 3, Title: "Old chore", Deadline: deadline, Done: true, Repeat: "weekly"},
+	}
+	events := string(exportICS(tasks, false, now))
+	for _, want := range []string{
+		"BEGIN:VEVENT\r\nUID:abc@todo\r\n",
+		"DTSTAMP:20260301T120000Z\r\n",
+		"SUMMARY:Pay rent\\, on time\r\n",
+		"DTSTART;VALUE=DATE:20260310\r\nDTEND;VALUE=DATE:20260311\r\n",
+		"RRULE:FREQ=MONTHLY\r\nPRIORITY:1\r\nCATEGORIES:home\r\n",
+		"UID:task-3@todo\r\n",
+		"STATUS:CANCELLED\r\n",
+	} {
+		if !strings.Contains(events, want) {
+			t.Errorf("exported events lack %q:\n%s", want, events)
+		}
+	}
+	if strings.Contains(events, "No deadline") {
+		t.Error("a task without a deadline was exported")
+	}
+	if strings.Count(events, "RRULE:") != 1 {
+		t.Error("the finished occurrence carries the repeat rule")
+	}
+
+	todos := string(exportICS(tasks, true, now))
+	for _, want := range []string{"BEGIN:VTODO\r\n", "DUE;VALUE=DATE:20260310\r\n", "STATUS:NEEDS-ACTION\r\n", "STATUS:COMPLETED\r\n"} {
+		if !strings.Contains(todos, want) {
+			t.Errorf("exported to-dos lack %q", want)
+		}
+	}
+	todo := parseVTODO(todos)
+	if todo.UID != "abc@todo" || todo.Summary != "Pay rent, on time" || !todo.Deadline.Equal(deadline) || todo.Done {
+		t.Errorf("reading back the export gave %+v", todo)
+	}
+}
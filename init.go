@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// configLine is one commented setting written by the init wizard
+type configLine struct {
+	Comment string
+	Key     string
+	Value   interface{}
+}
+
+// ask prompts for a line of text, returning def when the answer is empty
+func ask(reader *bufio.Reader, prompt, def string) string {
+	if def != "" {
+		prompt += " [" + def + "]"
+	}
+	fmt.Print(prompt + ": ")
+	line, _ := reader.ReadString('\n')
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// renderConfig writes settings as JSON with a comment above each one
+func renderConfig(lines []configLine) ([]byte, error) {
+	var out strings.Builder
+	out.WriteString("// Settings for todo, written by \"todo init\".\n// Lines starting with // are comments.\n{\n")
+	for i, line := range lines {
+		value, err := json.MarshalIndent(line.Value, "  ", "  ")
+		if err != nil {
+			return nil, err
+		}
+		comma := ","
+		if i == len(lines)-1 {
+			comma = ""
+		}
+		fmt.Fprintf(&out, "  // %s\n  %q: %s%s\n", line.Comment, line.Key, value, comma)
+	}
+	out.WriteString("}\n")
+	return []byte(out.String()), nil
+}
+
+// listenerUnit is a systemd user service running the quick-add listener
+const listenerUnit = `[Unit]
+Description=todo quick-add listener
+
+[Service]
+Environment=TODO_DIR=%s
+ExecStart=%s listen --socket
+Restart=on-failure
+
+[Install]
+WantedBy=default.target
+`
+
+// installListener writes the systemd user unit for "listen --socket"
+func installListener(dir string) (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	unitDir := filepath.Join(home, ".config", "systemd", "user")
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(unitDir, "todo-listen.service")
+	return path, os.WriteFile(path, []byte(fmt.Sprintf(listenerUnit, dir, self)), 0644)
+}
+
+// initWizard asks the questions needed to set up a data directory and
+// writes a commented config.json into it
+func initWizard(reader *bufio.Reader) error {
+	def := os.Getenv("TODO_DIR")
+	if def == "" {
+		def, _ = os.Getwd()
+	}
+	dir, err := filepath.Abs(ask(reader, "Where should tasks be stored?", def))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	os.Setenv("TODO_DIR", dir)
+	if _, err := os.Stat(dataPath("config.json")); err == nil && !confirm(reader, dataPath("config.json")+" exists. Replace it?") {
+		return fmt.Errorf("setup cancelled")
+	}
+
+	var lines []configLine
+	if project := ask(reader, "Default project for new tasks (empty for none)", ""); project != "" {
+		projects, err := loadProjects()
+		if err != nil {
+			return err
+		}
+		if findProject(projects, project) == nil {
+			projects = append(projects, Project{Name: project, Created: time.Now()})
+			if err := saveProjects(projects); err != nil {
+				return err
+			}
+		}
+		lines = append(lines, configLine{"Project used by add when no --project is given", "default_project", project})
+	}
+	if askChoice(reader, "Colors: [s]tandard or [a]ccessible (text markers, color-blind safe)? ", "sa") == "a" {
+		lines = append(lines, configLine{"Text markers and a color-blind safe palette", "accessible", true})
+	}
+	weekStart := "monday"
+	if firstWeekday() == time.Sunday {
+		weekStart = "sunday"
+	}
+	for {
+		answer := ask(reader, "First day of the week (monday or sunday)", weekStart)
+		if err := checkWeekStart(answer); err != nil {
+			fmt.Printf("%s%v%s\n", yellow, err, reset)
+			continue
+		}
+		weekStart = strings.ToLower(answer)
+		break
+	}
+	lines = append(lines, configLine{"First day of the week: monday or sunday", "week_start", weekStart})
+
+	if confirm(reader, "Sync with GitLab?") {
+		lines = append(lines, configLine{"Server and token variable for \"sync gitlab\"", "gitlab", GitLabConfig{
+			URL:      ask(reader, "  GitLab URL", "https://gitlab.com"),
+			TokenEnv: ask(reader, "  Environment variable holding the token", "GITLAB_TOKEN"),
+		}})
+	}
+	if confirm(reader, "Sync with Jira?") {
+		name := ask(reader, "  Name for this instance", "work")
+		lines = append(lines, configLine{"Instances for \"sync jira\", by name", "jira", map[string]JiraConfig{name: {
+			URL:      ask(reader, "  Jira URL", ""),
+			User:     ask(reader, "  User email", ""),
+			TokenEnv: ask(reader, "  Environment variable holding the API token", "JIRA_TOKEN"),
+		}}})
+	}
+	if confirm(reader, "Sync with Microsoft To Do?") {
+		lines = append(lines, configLine{"Azure app registration for \"sync mstodo\"", "microsoft", MicrosoftConfig{
+			ClientID: ask(reader, "  Application (client) ID", ""),
+		}})
+	}
+	if confirm(reader, "Sync checklists from a Markdown notes folder?") {
+		lines = append(lines, configLine{"Notes folder for \"sync vault\"", "vault", VaultConfig{
+			Path: ask(reader, "  Folder", ""),
+		}})
+	}
+
+	data, err := renderConfig(lines)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dataPath("config.json"), data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("%sWrote %s%s\n", green, dataPath("config.json"), reset)
+
+	if confirm(reader, "Install the quick-add listener as a systemd user service?") {
+		path, err := installListener(dir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%sWrote %s%s\n", green, path, reset)
+		fmt.Println("Start it with: systemctl --user enable --now todo-listen")
+	}
+	if cwd, _ := os.Getwd(); cwd != dir {
+		fmt.Printf("Add this to your shell profile so todo finds your tasks:\n  export TODO_DIR=%q\n", dir)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// JiraConfig describes one Jira instance to sync with
+type JiraConfig struct {
+	URL  string `json:"url"`
+	User string `json:"user"`
+	// TokenEnv names the environment variable holding the API token
+	// (JIRA_TOKEN if empty)
+	TokenEnv string `json:"token_env,omitempty"`
+	// JQL is the query used when "sync jira" is run without --jql and by
+	// "sync all"
+	JQL string `json:"jql,omitempty"`
+	// DoneTransition is applied to issues whose task has been completed.
+	// Issues are left alone when it is empty.
+	DoneTransition string `json:"done_transition,omitempty"`
+	// TitleField and DeadlineField name the issue fields mapped onto the
+	// task (summary and duedate if empty)
+	TitleField    string `json:"title_field,omitempty"`
+	DeadlineField string `json:"deadline_field,omitempty"`
+}
+
+// jiraIssue is the part of a Jira search result used for syncing
+type jiraIssue struct {
+	Key    string                     `json:"key"`
+	Fields map[string]json.RawMessage `json:"fields"`
+}
+
+// jiraClient talks to a single Jira instance
+type jiraClient struct {
+	cfg   JiraConfig
+	token string
+}
+
+// newJiraClient validates an instance's settings
+func newJiraClient(cfg JiraConfig) (*jiraClient, error) {
+	if cfg.URL == "" || cfg.User == "" {
+		return nil, fmt.Errorf("jira instance needs url and user")
+	}
+	if cfg.TokenEnv == "" {
+		cfg.TokenEnv = "JIRA_TOKEN"
+	}
+	if cfg.TitleField == "" {
+		cfg.TitleField = "summary"
+	}
+	if cfg.DeadlineField == "" {
+		cfg.DeadlineField = "duedate"
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	token := providerToken(cfg.TokenEnv, "jira "+cfg.URL)
+	if token == "" {
+		return nil, fmt.Errorf("jira API token not set, export %s or run \"auth login jira\"", cfg.TokenEnv)
+	}
+	return &jiraClient{cfg: cfg, token: token}, nil
+}
+
+// newRequest builds an authenticated request against the REST API
+func (c *jiraClient) newRequest(method, path string) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.cfg.URL+"/rest/api/2"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.cfg.User, c.token)
+	return req, nil
+}
+
+// search returns every issue matching the JQL query
+func (c *jiraClient) search(jql string) ([]jiraIssue, error) {
+	var issues []jiraIssue
+	for {
+		req, err := c.newRequest("POST", "/search")
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Total  int         `json:"total"`
+			Issues []jiraIssue `json:"issues"`
+		}
+		body := map[string]interface{}{
+			"jql":        jql,
+			"startAt":    len(issues),
+			"maxResults": 50,
+			"fields":     []string{c.cfg.TitleField, c.cfg.DeadlineField},
+		}
+		if err := doJSON(req, body, &page); err != nil {
+			return nil, err
+		}
+		issues = append(issues, page.Issues...)
+		if len(page.Issues) == 0 || len(issues) >= page.Total {
+			return issues, nil
+		}
+	}
+}
+
+// transition moves an issue through the workflow transition with the
+// given name
+func (c *jiraClient) transition(key, name string) error {
+	path := "/issue/" + url.PathEscape(key) + "/transitions"
+	req, err := c.newRequest("GET", path)
+	if err != nil {
+		return err
+	}
+	var available struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	if err := doJSON(req, nil, &available); err != nil {
+		return err
+	}
+
+	for _, t := range available.Transitions {
+		if strings.EqualFold(t.Name, name) {
+			req, err := c.newRequest("POST", path)
+			if err != nil {
+				return err
+			}
+			body := map[string]interface{}{"transition": map[string]string{"id": t.ID}}
+			return doJSON(req, body, nil)
+		}
+	}
+	return fmt.Errorf("%s: no transition named %q", key, name)
+}
+
+// item converts an issue into a remote item using the field mapping
+func (c *jiraClient) item(source string, issue jiraIssue) remoteItem {
+	item := remoteItem{Remote: Remote{Source: source, ID: issue.Key, URL: c.cfg.URL + "/browse/" + issue.Key}}
+
+	var title string
+	json.Unmarshal(issue.Fields[c.cfg.TitleField], &title)
+	item.Title = issue.Key + ": " + title
+
+	var due string
+	json.Unmarshal(issue.Fields[c.cfg.DeadlineField], &due)
+	if len(due) >= 10 {
+		if parsed, err := time.Parse("2006-01-02", due[:10]); err == nil {
+			item.Deadline = parsed
+		}
+	}
+	return item
+}
+
+// syncJira imports issues matching jql and transitions the issues whose
+// task has already been completed locally
+func syncJira(tasks []Task, name string, cfg JiraConfig, jql string) ([]Task, error) {
+	client, err := newJiraClient(cfg)
+	if err != nil {
+		return tasks, err
+	}
+	issues, err := client.search(jql)
+	if err != nil {
+		return tasks, err
+	}
+
+	source := "jira:" + name
+	var items []remoteItem
+	transitioned := 0
+	for _, issue := range issues {
+		item := client.item(source, issue)
+		task := findRemote(tasks, item.Remote)
+		if task != nil && task.Done && client.cfg.DoneTransition != "" {
+			if err := client.transition(issue.Key, client.cfg.DoneTransition); err != nil {
+				return tasks, err
+			}
+			transitioned++
+			continue
+		}
+		items = append(items, item)
+	}
+
+	var added, updated int
+	tasks, added, updated = applyRemoteItems(tasks, items)
+	fmt.Printf("%sJira %s: %d added, %d updated, %d transitioned%s\n", green, name, added, updated, transitioned, reset)
+	return tasks, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestSyncJiraReplay(t *testing.T) {
+	t.Setenv("JIRA_TOKEN", "test-token")
+	withConfig(t, Config{Jira: map[string]JiraConfig{"work": {
+		URL:            "https://jira.example.com",
+		User:           "me@example.com",
+		JQL:            "assignee = currentUser()",
+		DoneTransition: "done",
+	}}})
+	tasks := []Task{
+		{ID: 1, Title: "WORK-1: Fix login", Remotes: []Remote{{Source: "jira:work", ID: "WORK-1"}}},
+		{ID: 2, Title: "WORK-2: Deploy release", Done: true, Remotes: []Remote{{Source: "jira:work", ID: "WORK-2"}}},
+		// Already imported from GitLab, so the issue is linked, not added
+		{ID: 3, Title: "group/app#5: Write changelog", Remotes: []Remote{{Source: "gitlab", ID: "issue:105"}}},
+	}
+	tasks = replay(t, tasks, "jira", "--replay-http", "testdata/jira.jsonl")
+
+	if len(tasks) != 3 {
+		t.Fatalf("got %d tasks, want 3: %+v", len(tasks), tasks)
+	}
+	if got := findTask(tasks, 1).Deadline.Format("2006-01-02"); got != "2026-03-20" {
+		t.Errorf("WORK-1 deadline %s, want 2026-03-20", got)
+	}
+	linked := findTask(tasks, 3)
+	if findRemote([]Task{*linked}, Remote{Source: "jira:work", ID: "WORK-3"}) == nil {
+		t.Errorf("WORK-3 not linked to the GitLab task: %+v", linked.Remotes)
+	}
+	if linked.Remotes[1].URL != "https://jira.example.com/browse/WORK-3" {
+		t.Errorf("WORK-3 link %q", linked.Remotes[1].URL)
+	}
+}
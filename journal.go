@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// journalLimit is how many operations are kept in the journal
+const journalLimit = 200
+
+// Operation is a journal entry recording the tasks changed by one command
+type Operation struct {
+	ID      int       `json:"id"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Before  []Task    `json:"before"`
+	After   []Task    `json:"after"`
+	// Undoes is the ID of the operation an undo reverted
+	Undoes int `json:"undoes,omitempty"`
+}
+
+// loadJournal reads operations from journal.txt file
+func loadJournal() ([]Operation, error) {
+	file, err := os.ReadFile(dataPath("journal.txt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Operation{}, nil
+		}
+		return nil, err
+	}
+
+	var ops []Operation
+	if err := json.Unmarshal(file, &ops); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// saveJournal writes operations to journal.txt file
+func saveJournal(ops []Operation) error {
+	if len(ops) > journalLimit {
+		ops = ops[len(ops)-journalLimit:]
+	}
+	data, err := json.MarshalIndent(ops, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dataPath("journal.txt"), data, 0644)
+}
+
+// recordOperation appends the difference between two task lists to the journal
+func recordOperation(command string, before, after []Task) error {
+	return appendOperation(Operation{Command: command}, before, after)
+}
+
+// recordUndo journals the revert of the operation with ID undone
+func recordUndo(undone int, before, after []Task) error {
+	return appendOperation(Operation{Command: "undo", Undoes: undone}, before, after)
+}
+
+// appendOperation fills in an operation's ID, time and changed tasks and
+// adds it to the journal
+func appendOperation(op Operation, before, after []Task) error {
+	ops, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	op.ID = 1
+	if len(ops) > 0 {
+		op.ID = ops[len(ops)-1].ID + 1
+	}
+	op.Time = time.Now()
+	op.Before, op.After = diffTasks(before, after)
+	return saveJournal(append(ops, op))
+}
+
+// lastUndoable returns the newest operation that is not an undo and has
+// not been undone, so repeated undos walk back through the journal
+func lastUndoable(ops []Operation) *Operation {
+	undone := map[int]bool{}
+	for i := len(ops) - 1; i >= 0; i-- {
+		switch {
+		case ops[i].Undoes != 0:
+			undone[ops[i].Undoes] = true
+		case ops[i].Command == "undo":
+			// Undone before undos were linked to their operation
+		case !undone[ops[i].ID]:
+			return &ops[i]
+		}
+	}
+	return nil
+}
+
+// copyTasks returns a deep copy of tasks so later edits don't affect it
+func copyTasks(tasks []Task) []Task {
+	data, err := json.Marshal(tasks)
+	if err != nil {
+		return append([]Task(nil), tasks...)
+	}
+	var copied []Task
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return append([]Task(nil), tasks...)
+	}
+	return copied
+}
+
+// sameTask reports whether two (possibly missing) tasks are identical
+func sameTask(a, b *Task) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	aj, _ := json.Marshal(a)
+	bj, _ := json.Marshal(b)
+	return string(aj) == string(bj)
+}
+
+// findTask returns a pointer to the task with the given ID, or nil
+func findTask(tasks []Task, id int) *Task {
+	for i := range tasks {
+		if tasks[i].ID == id {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+// diffTasks returns the old and new versions of every task that differs
+func diffTasks(before, after []Task) ([]Task, []Task) {
+	var changedBefore, changedAfter []Task
+	for _, task := range before {
+		newer := findTask(after, task.ID)
+		if !sameTask(&task, newer) {
+			changedBefore = append(changedBefore, task)
+			if newer != nil {
+				changedAfter = append(changedAfter, *newer)
+			}
+		}
+	}
+	for _, task := range after {
+		if findTask(before, task.ID) == nil {
+			changedAfter = append(changedAfter, task)
+		}
+	}
+	return changedBefore, changedAfter
+}
+
+// tasksChanged reports whether any task was added, removed or modified
+func tasksChanged(before, after []Task) bool {
+	changedBefore, changedAfter := diffTasks(before, after)
+	return len(changedBefore) > 0 || len(changedAfter) > 0
+}
+
+// affectedIDs lists the IDs of all tasks touched by an operation
+func (op Operation) affectedIDs() []int {
+	var ids []int
+	seen := map[int]bool{}
+	for _, list := range [][]Task{op.Before, op.After} {
+		for _, task := range list {
+			if !seen[task.ID] {
+				seen[task.ID] = true
+				ids = append(ids, task.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// describe summarizes an operation, e.g. "done #14 'pay rent'"
+func (op Operation) describe() string {
+	ids := op.affectedIDs()
+	if len(ids) == 1 {
+		task := findTask(op.After, ids[0])
+		if task == nil {
+			task = findTask(op.Before, ids[0])
+		}
+		return fmt.Sprintf("%s #%d '%s'", op.Command, task.ID, task.Title)
+	}
+	if len(ids) > 3 {
+		return fmt.Sprintf("%s %d tasks", op.Command, len(ids))
+	}
+	refs := make([]string, len(ids))
+	for i, id := range ids {
+		refs[i] = fmt.Sprintf("#%d", id)
+	}
+	return op.Command + " " + strings.Join(refs, ", ")
+}
+
+// revertOperation restores the tasks an operation changed, refusing if any of
+// them has been modified since
+func revertOperation(tasks []Task, op Operation) ([]Task, error) {
+	for _, id := range op.affectedIDs() {
+		if !sameTask(findTask(tasks, id), findTask(op.After, id)) {
+			return tasks, fmt.Errorf("task #%d has changed since operation %d", id, op.ID)
+		}
+	}
+
+	for _, id := range op.affectedIDs() {
+		old := findTask(op.Before, id)
+		current := findTask(tasks, id)
+		switch {
+		case old == nil:
+			tasks, _ = deleteTask(tasks, id)
+		case current != nil:
+			*current = *old
+		default:
+			tasks = insertTask(tasks, *old)
+		}
+	}
+	return tasks, nil
+}
+
+// insertTask puts a task back in ID order
+func insertTask(tasks []Task, task Task) []Task {
+	for i := range tasks {
+		if tasks[i].ID > task.ID {
+			tasks = append(tasks[:i+1], tasks[i:]...)
+			tasks[i] = task
+			return tasks
+		}
+	}
+	return append(tasks, task)
+}
+
+// completedBetween returns the tasks marked done by operations in [from, to)
+func completedBetween(ops []Operation, from, to time.Time) []Task {
+	var done []Task
+	for _, op := range ops {
+		if op.Time.Before(from) || !op.Time.Before(to) {
+			continue
+		}
+		for _, task := range op.After {
+			old := findTask(op.Before, task.ID)
+			if task.Done && (old == nil || !old.Done) {
+				done = append(done, task)
+			}
+		}
+	}
+	return done
+}
+
+// completedTasks returns the tasks completed in [from, to) that are still
+// done now, each listed once. Completion times come from the tasks; the
+// journal, which keeps only the most recent operations, is read only for
+// tasks finished before those were recorded.
+func completedTasks(tasks []Task, ops []Operation, from, to time.Time) []Task {
+	var done []Task
+	for _, task := range tasks {
+		if task.Done && !task.CompletedAt.IsZero() && !task.CompletedAt.Before(from) && task.CompletedAt.Before(to) {
+			done = append(done, task)
+		}
+	}
+	for _, task := range completedBetween(ops, from, to) {
+		current := findTask(tasks, task.ID)
+		if current == nil || !current.Done || !current.CompletedAt.IsZero() || findTask(done, task.ID) != nil {
+			continue
+		}
+		done = append(done, task)
+	}
+	return done
+}
+
+// printJournal shows the most recent operations, oldest first
+func printJournal(ops []Operation, limit int) {
+	if len(ops) == 0 {
+		fmt.Println(yellow + "Journal is empty" + reset)
+		return
+	}
+	if limit > 0 && len(ops) > limit {
+		ops = ops[len(ops)-limit:]
+	}
+	for _, op := range ops {
+		fmt.Printf("%4d  %s  %s\n", op.ID, op.Time.Format("2006-01-02 15:04"), op.describe())
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// jsonExportVersion is the version of the JSON export format
+const jsonExportVersion = 1
+
+// jsonExport is the document written by "export --format json". Tasks are
+// matched across machines by UID; IDs are local to each machine.
+type jsonExport struct {
+	Format   string    `json:"format"`
+	Version  int       `json:"version"`
+	Exported time.Time `json:"exported"`
+	Tasks    []Task    `json:"tasks"`
+}
+
+// newUID returns a random identifier that stays with a task everywhere
+func newUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// ensureUIDs gives tasks from before UIDs existed one of their own. It
+// runs on every load, and the UID is derived from the task rather than
+// random, so read-only commands such as export see the same UID each time
+// without saving it; the next save of the list keeps it for good.
+func ensureUIDs(tasks []Task) []Task {
+	for i := range tasks {
+		if tasks[i].UID == "" {
+			key := fmt.Sprintf("%d\n%s\n%s", tasks[i].ID, tasks[i].CreatedAt.Format(time.RFC3339Nano), tasks[i].Title)
+			sum := sha256.Sum256([]byte(key))
+			tasks[i].UID = hex.EncodeToString(sum[:16])
+		}
+	}
+	return tasks
+}
+
+// findUID returns the task with the given UID, or nil
+func findUID(tasks []Task, uid string) *Task {
+	for i := range tasks {
+		if tasks[i].UID == uid {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+// exportJSON writes tasks in the JSON export format
+func exportJSON(tasks []Task) ([]byte, error) {
+	return json.MarshalIndent(jsonExport{Format: "todo", Version: jsonExportVersion, Exported: time.Now(), Tasks: tasks}, "", "  ")
+}
+
+// mergeTask folds another machine's copy of a task into the local one
+// without losing either side: a task done on either side is done, tags and
+// work sessions are combined, and empty fields are filled in. Where both
+// sides set a field differently the local value stays. It reports whether
+// anything changed.
+func mergeTask(local *Task, other Task) bool {
+	before, _ := json.Marshal(local)
+	if other.Done && !local.Done {
+		local.setDone(true)
+		local.CompletedAt = other.CompletedAt
+	}
+	if local.Deadline.IsZero() {
+		local.Deadline = other.Deadline
+	}
+	if local.Project == "" {
+		local.Project = other.Project
+	}
+	if local.Priority == priorityNone {
+		local.Priority = other.Priority
+	}
+	if local.Repeat == "" {
+		local.Repeat, local.RepeatFrom = other.Repeat, other.RepeatFrom
+		local.Paused, local.PausedUntil = other.Paused, other.PausedUntil
+	}
+	if other.Notes != "" && !strings.Contains(local.Notes, other.Notes) {
+		local.Notes = appendNote(local.Notes, other.Notes)
+	}
+	local.Tags = addTags(local.Tags, other.Tags)
+	for _, session := range other.Sessions {
+		known := false
+		for _, mine := range local.Sessions {
+			known = known || mine.Start.Equal(session.Start)
+		}
+		if !known {
+			local.Sessions = append(local.Sessions, session)
+		}
+	}
+	if other.Pomodoros > local.Pomodoros {
+		local.Pomodoros = other.Pomodoros
+	}
+	if local.CreatedAt.IsZero() || (!other.CreatedAt.IsZero() && other.CreatedAt.Before(local.CreatedAt)) {
+		local.CreatedAt = other.CreatedAt
+	}
+	for _, remote := range other.Remotes {
+		if findRemote([]Task{*local}, remote) == nil {
+			local.Remotes = append(local.Remotes, remote)
+		}
+	}
+	after, _ := json.Marshal(local)
+	return string(before) != string(after)
+}
+
+// importJSON reads a JSON export. With merge set, tasks are matched by UID:
+// known ones are merged and the rest added with new local IDs. Without it
+// the import only goes into an empty list, so nothing is overwritten.
+func importJSON(tasks []Task, data []byte, merge bool) ([]Task, int, int, error) {
+	var doc jsonExport
+	if err := json.Unmarshal(data, &doc); err != nil || doc.Format != "todo" {
+		return tasks, 0, 0, fmt.Errorf("not a todo JSON export")
+	}
+	if doc.Version > jsonExportVersion {
+		return tasks, 0, 0, fmt.Errorf("export format version %d is newer than this program supports", doc.Version)
+	}
+	if len(tasks) > 0 && !merge {
+		return tasks, 0, 0, fmt.Errorf("the list already has tasks, use --merge to combine them with the import")
+	}
+	tasks = ensureUIDs(tasks)
+
+	var added, updated int
+	localID := map[int]int{}
+	isNew := map[int]bool{}
+	for _, incoming := range doc.Tasks {
+		if incoming.UID == "" {
+			incoming.UID = newUID()
+		}
+		if !incoming.Deadline.IsZero() {
+			incoming.Deadline = deadlineOn(incoming.Deadline)
+		}
+		if local := findUID(tasks, incoming.UID); local != nil {
+			localID[incoming.ID] = local.ID
+			if mergeTask(local, incoming) {
+				updated++
+			}
+			continue
+		}
+		var id int
+		tasks, id = addTask(tasks, incoming.Title, "")
+		localID[incoming.ID] = id
+		isNew[id] = true
+		incoming.ID = id
+		*findTask(tasks, id) = incoming
+		added++
+	}
+	// Parent IDs in the file are the other machine's; point subtasks at
+	// the local IDs of their parents
+	for _, incoming := range doc.Tasks {
+		task := findTask(tasks, localID[incoming.ID])
+		parent, known := localID[incoming.ParentID]
+		switch {
+		case isNew[task.ID] && known:
+			task.ParentID = parent
+		case isNew[task.ID]:
+			task.ParentID = 0
+		case task.ParentID == 0 && known:
+			task.ParentID = parent
+		}
+	}
+	return tasks, added, updated, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// supportsHyperlinks reports whether stdout is a terminal known to render
+// OSC 8 hyperlinks. FORCE_HYPERLINK=1 or 0 overrides the detection.
+func supportsHyperlinks() bool {
+	if force := os.Getenv("FORCE_HYPERLINK"); force != "" {
+		return force != "0"
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "Hyper", "ghostty":
+		return true
+	}
+	if vte, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && vte >= 5000 {
+		return true
+	}
+	term := os.Getenv("TERM")
+	return os.Getenv("WT_SESSION") != "" || os.Getenv("KITTY_WINDOW_ID") != "" ||
+		strings.Contains(term, "kitty") || strings.Contains(term, "alacritty") || strings.Contains(term, "foot")
+}
+
+// hyperlinks is decided once per run
+var hyperlinks = supportsHyperlinks()
+
+// hyperlink wraps text in an OSC 8 escape pointing at target
+func hyperlink(text, target string) string {
+	return "\033]8;;" + target + "\033\\" + text + "\033]8;;\033\\"
+}
+
+// linkURLs makes every URL in text clickable when the terminal supports it
+func linkURLs(text string) string {
+	if !hyperlinks {
+		return text
+	}
+	return urlPattern.ReplaceAllStringFunc(text, func(u string) string {
+		return hyperlink(u, u)
+	})
+}
+
+// remoteLabel shows a remote reference as a clickable label, or followed
+// by its URL when links can't be rendered
+func remoteLabel(remote Remote) string {
+	label := "[" + remote.Source + " " + remote.ID + "]"
+	switch {
+	case remote.URL == "":
+		return label
+	case hyperlinks:
+		return hyperlink(label, remote.URL)
+	default:
+		return label + " " + remote.URL
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// parseQuickAdd splits a quick-add string into a title and a deadline
+// given as a trailing "YYYY-MM-DD" or a "due:YYYY-MM-DD" word
+func parseQuickAdd(line string) (string, string) {
+	var title []string
+	deadline := ""
+	words := strings.Fields(line)
+	for i, word := range words {
+		if strings.HasPrefix(word, "due:") {
+			deadline = strings.TrimPrefix(word, "due:")
+			continue
+		}
+		if i == len(words)-1 && len(title) > 0 {
+			if _, err := time.Parse("2006-01-02", word); err == nil {
+				deadline = word
+				continue
+			}
+		}
+		title = append(title, word)
+	}
+	return strings.Join(title, " "), deadline
+}
+
+// quickAdd adds a task from a quick-add string straight to the store,
+// putting it in the default project as add does
+func quickAdd(line string) (int, string, error) {
+	title, deadline := parseQuickAdd(line)
+	if title == "" {
+		return 0, "", fmt.Errorf("task title is required")
+	}
+	var project *Project
+	if config.DefaultProject != "" {
+		var err error
+		if project, err = openProject(config.DefaultProject); err != nil {
+			return 0, "", err
+		}
+	}
+	tasks, err := loadTasks()
+	if err != nil {
+		return 0, "", err
+	}
+	original := copyTasks(tasks)
+	tasks, id := addTask(tasks, title, deadline)
+	if project != nil {
+		project.join(findTask(tasks, id))
+	}
+	if err := saveTasks(tasks); err != nil {
+		return 0, "", err
+	}
+	return id, title, recordOperation("add", original, tasks)
+}
+
+// listen accepts quick-add strings, one per line, on a unix socket until
+// interrupted, replying to each with the result
+func listen(path string) error {
+	// A socket left behind by a crashed listener would block the address
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return fmt.Errorf("another listener is using %s", path)
+		}
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		listener.Close()
+	}()
+
+	fmt.Printf("%sListening for tasks on %s%s\n", green, path, reset)
+	var store sync.Mutex
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if line == "" {
+					continue
+				}
+				store.Lock()
+				id, title, err := quickAdd(line)
+				store.Unlock()
+				if err != nil {
+					fmt.Fprintf(conn, "error: %v\n", err)
+					continue
+				}
+				fmt.Printf("Added task #%d: %s\n", id, title)
+				fmt.Fprintf(conn, "added #%d\n", id)
+			}
+		}()
+	}
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"sort"
 	"strconv"
-	
+	"strings"
+
 	"time"
 )
 
@@ -15,11 +20,36 @@ type Task struct {
 	Title    string    `json:"title"`
 	Done     bool      `json:"done"`
 	Deadline time.Time `json:"deadline,omitempty"`
+	ParentID int       `json:"parent_id,omitempty"`
+	Remotes  []Remote  `json:"remotes,omitempty"`
+	Project  string    `json:"project,omitempty"`
+	Priority int       `json:"priority,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+	Pinned   bool      `json:"pinned,omitempty"`
+	Notes    string    `json:"notes,omitempty"`
+	// Repeat is daily, weekly, monthly, yearly, an interval such as 30d,
+	// a cron expression or an iCalendar RRULE
+	Repeat string `json:"repeat,omitempty"`
+	// RepeatFrom is "done" when the next occurrence counts from the day
+	// the task was finished rather than from its deadline
+	RepeatFrom string `json:"repeat_from,omitempty"`
+	// Paused puts a recurring task on hold, until PausedUntil when set
+	Paused      bool      `json:"paused,omitempty"`
+	PausedUntil time.Time `json:"paused_until,omitempty"`
+	// Sessions are the tracked stretches of work on the task
+	Sessions []Session `json:"sessions,omitempty"`
+	// Pomodoros counts the work periods finished with "pomodoro"
+	Pomodoros int `json:"pomodoros,omitempty"`
+	// CreatedAt and CompletedAt are when the task was added and finished
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+	// UID identifies the task across machines for JSON import and export
+	UID string `json:"uid,omitempty"`
 }
 
 // loadTasks reads tasks from tasks.txt file
 func loadTasks() ([]Task, error) {
-	file, err := os.ReadFile("tasks.txt")
+	file, err := os.ReadFile(dataPath("tasks.txt"))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return []Task{}, nil
@@ -28,10 +58,21 @@ func loadTasks() ([]Task, error) {
 	}
 
 	var tasks []Task
+	sumErr := verifyChecksum(file)
 	if err := json.Unmarshal(file, &tasks); err != nil {
+		if sumErr != nil {
+			// Still hand back what can be read so verify can report on it
+			return tasks, errors.Join(sumErr, err)
+		}
 		return nil, err
 	}
-	return tasks, nil
+	for i := range tasks {
+		// Older versions saved some deadlines in the local zone
+		if !tasks[i].Deadline.IsZero() {
+			tasks[i].Deadline = deadlineOn(tasks[i].Deadline)
+		}
+	}
+	return ensureUIDs(tasks), sumErr
 }
 
 // saveTasks writes tasks to tasks.txt file
@@ -40,23 +81,22 @@ func saveTasks(tasks []Task) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile("tasks.txt", data, 0644)
+	if err := os.WriteFile(dataPath("tasks.txt"), data, 0644); err != nil {
+		return err
+	}
+	return writeChecksum(data)
 }
 
 // addTask creates a new task and adds it to the list
 func addTask(tasks []Task, title string, deadline string) ([]Task, int) {
-	var newID int
-	if len(tasks) == 0 {
-		newID = 1
-	} else {
-		maxID := tasks[0].ID
-		for _, task := range tasks[1:] {
-			if task.ID > maxID {
-				maxID = task.ID
-			}
+	// IDs of archived tasks stay taken
+	maxID := archivedMaxID()
+	for _, task := range tasks {
+		if task.ID > maxID {
+			maxID = task.ID
 		}
-		newID = maxID + 1
 	}
+	newID := maxID + 1
 
 	var dl time.Time
 	if deadline != "" {
@@ -67,10 +107,12 @@ func addTask(tasks []Task, title string, deadline string) ([]Task, int) {
 	}
 
 	newTask := Task{
-		ID:       newID,
-		Title:    title,
-		Done:     false,
-		Deadline: dl,
+		ID:        newID,
+		Title:     title,
+		Done:      false,
+		Deadline:  dl,
+		CreatedAt: time.Now(),
+		UID:       newUID(),
 	}
 
 	tasks = append(tasks, newTask)
@@ -87,17 +129,267 @@ func deleteTask(tasks []Task, id int) ([]Task, bool) {
 	return tasks, false
 }
 
+// setDone marks a task done or not done, stamping when it was completed
+func (t *Task) setDone(done bool) {
+	switch {
+	case done && !t.Done:
+		t.CompletedAt = time.Now()
+	case !done:
+		t.CompletedAt = time.Time{}
+	}
+	t.Done = done
+}
+
 // markDone sets a task as done by ID
 func markDone(tasks []Task, id int) ([]Task, bool) {
 	for i := range tasks {
 		if tasks[i].ID == id {
-			tasks[i].Done = true
+			tasks[i].setDone(true)
+			return tasks, true
+		}
+	}
+	return tasks, false
+}
+
+// completion is what completeTask did besides marking the task done
+type completion struct {
+	stopped bool  // a running work session was stopped
+	parents []int // ancestors done now that all their subtasks are
+	nextID  int   // the next occurrence of a recurring task, or 0
+}
+
+// completeTask finishes a task the way "done" does: it refuses while
+// subtasks are open, stops time tracking, completes parents whose
+// subtasks are now all done and adds the next occurrence of a recurring
+// task. An error from the last step leaves the task done.
+func completeTask(tasks []Task, id int, now time.Time) ([]Task, completion, error) {
+	var done completion
+	task := findTask(tasks, id)
+	if task == nil {
+		return tasks, done, fmt.Errorf("task #%d not found", id)
+	}
+	if hasPendingSubtasks(tasks, id) {
+		return tasks, done, fmt.Errorf("task #%d has open subtasks, finish those first", id)
+	}
+	if task.tracking() {
+		tasks, _, _ = stopTracking(tasks, id, now)
+		done.stopped = true
+	}
+	tasks, _ = markDone(tasks, id)
+	done.parents = completeParents(tasks, id)
+	var err error
+	tasks, done.nextID, err = repeatTask(tasks, id, now)
+	return tasks, done, err
+}
+
+// markUndone sets a task back to not done by ID
+func markUndone(tasks []Task, id int) ([]Task, bool) {
+	for i := range tasks {
+		if tasks[i].ID == id {
+			tasks[i].setDone(false)
 			return tasks, true
 		}
 	}
 	return tasks, false
 }
 
+// editTask changes the title and deadline of a task in place. A nil
+// argument leaves that field alone; a deadline of "" or "none" clears it.
+func editTask(tasks []Task, id int, title, deadline *string) ([]Task, error) {
+	task := findTask(tasks, id)
+	if task == nil {
+		return tasks, fmt.Errorf("task #%d not found", id)
+	}
+	if title != nil {
+		if strings.TrimSpace(*title) == "" {
+			return tasks, fmt.Errorf("title cannot be empty")
+		}
+		task.Title = *title
+	}
+	if deadline != nil {
+		if *deadline == "" || *deadline == "none" {
+			task.Deadline = time.Time{}
+		} else {
+			parsed, err := time.Parse("2006-01-02", *deadline)
+			if err != nil {
+				return tasks, fmt.Errorf("invalid deadline %q, expected YYYY-MM-DD", *deadline)
+			}
+			task.Deadline = parsed
+		}
+	}
+	return tasks, nil
+}
+
+// splitTask turns a task into a parent of new subtasks with the given
+// titles. The subtasks carry over its deadline, tags, project and priority.
+func splitTask(tasks []Task, id int, titles []string) ([]Task, []int, bool) {
+	parent := findTask(tasks, id)
+	if parent == nil {
+		return tasks, nil, false
+	}
+	from := *parent
+
+	var ids []int
+	for _, title := range titles {
+		var newID int
+		tasks, newID = addTask(tasks, title, "")
+		child := findTask(tasks, newID)
+		child.ParentID = id
+		child.Deadline = from.Deadline
+		child.Tags = addTags(nil, from.Tags)
+		child.Project = from.Project
+		child.Priority = from.Priority
+		ids = append(ids, newID)
+	}
+	return tasks, ids, true
+}
+
+// mergeTasks folds duplicate tasks into the first one: tags, remote links,
+// notes and tracked time are combined, and the nearest deadline, highest
+// priority and earliest creation time are kept. Subtasks move over to it.
+// It returns how many tasks were merged away.
+func mergeTasks(tasks []Task, ids []int) ([]Task, int, error) {
+	seen := map[int]bool{ids[0]: true}
+	var dups []int
+	for _, id := range ids[1:] {
+		if id == ids[0] {
+			return tasks, 0, fmt.Errorf("cannot merge task #%d into itself", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			dups = append(dups, id)
+		}
+	}
+	for id := range seen {
+		if findTask(tasks, id) == nil {
+			return tasks, 0, fmt.Errorf("task #%d not found", id)
+		}
+	}
+
+	keep := findTask(tasks, ids[0])
+	for _, id := range dups {
+		dup := findTask(tasks, id)
+		if keep.Deadline.IsZero() || (!dup.Deadline.IsZero() && dup.Deadline.Before(keep.Deadline)) {
+			keep.Deadline = dup.Deadline
+		}
+		keep.setDone(keep.Done && dup.Done)
+		if dup.Priority > keep.Priority {
+			keep.Priority = dup.Priority
+		}
+		if keep.CreatedAt.IsZero() || (!dup.CreatedAt.IsZero() && dup.CreatedAt.Before(keep.CreatedAt)) {
+			keep.CreatedAt = dup.CreatedAt
+		}
+		if keep.Project == "" {
+			keep.Project = dup.Project
+		}
+		if keep.Repeat == "" {
+			keep.Repeat, keep.RepeatFrom = dup.Repeat, dup.RepeatFrom
+			keep.Paused, keep.PausedUntil = dup.Paused, dup.PausedUntil
+		}
+		switch {
+		case keep.Notes == "":
+			keep.Notes = dup.Notes
+		case dup.Notes != "":
+			keep.Notes += "\n\n" + dup.Notes
+		}
+		keep.Pinned = keep.Pinned || dup.Pinned
+		keep.Pomodoros += dup.Pomodoros
+		keep.Sessions = mergeSessions(keep.Sessions, dup.Sessions)
+		for _, remote := range dup.Remotes {
+			if findRemote([]Task{*keep}, remote) == nil {
+				keep.Remotes = append(keep.Remotes, remote)
+			}
+		}
+		keep.Tags = addTags(keep.Tags, dup.Tags)
+	}
+
+	keepID := keep.ID
+	for _, id := range dups {
+		dupParent := findTask(tasks, id).ParentID
+		for i := range tasks {
+			if tasks[i].ParentID != id {
+				continue
+			}
+			if tasks[i].ID == keepID {
+				tasks[i].ParentID = dupParent
+			} else {
+				tasks[i].ParentID = keepID
+			}
+		}
+		tasks, _ = deleteTask(tasks, id)
+	}
+	return tasks, len(dups), nil
+}
+
+// mergeSessions combines the work sessions of two tasks in time order. If
+// both were being tracked, the earlier running session ends where the next
+// one starts so that only one stays open.
+func mergeSessions(a, b []Session) []Session {
+	if len(b) == 0 {
+		return a
+	}
+	merged := append(append([]Session{}, a...), b...)
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Start.Before(merged[j].Start) })
+	for i := 0; i < len(merged)-1; i++ {
+		if merged[i].End.IsZero() {
+			merged[i].End = merged[i+1].Start
+		}
+	}
+	return merged
+}
+
+// printTaskTree lists tasks with subtasks indented under their parent.
+// Subtasks whose parent no longer exists are shown at the top level.
+func printTaskTree(tasks []Task, parentID int, depth int) {
+	for _, task := range tasks {
+		orphan := depth == 0 && findTask(tasks, task.ParentID) == nil
+		if task.ParentID != parentID && !orphan {
+			continue
+		}
+		fmt.Println(taskLine(task, depth, linkURLs))
+		printTaskTree(tasks, task.ID, depth+1)
+	}
+}
+
+// taskLine renders a task as one line of a list, indented by depth. The
+// title is shortened to fit before decorate is applied to it.
+func taskLine(task Task, depth int, decorate func(string) string) string {
+	status := red + "Not Done" + reset
+	if task.Done {
+		status = green + "Done" + reset
+	}
+	dl := ""
+	if !task.Deadline.IsZero() {
+		left := ""
+		if showCountdown && !task.Done {
+			left = ", " + countdown(task, time.Now())
+		}
+		dl = " (Deadline: " + task.Deadline.Format("2006-01-02") + left + ")" + deadlineMarker(task, time.Now())
+		if isOverdue(task, time.Now()) {
+			dl = red + dl + reset
+		} else if !task.Done && isDueToday(task, time.Now()) {
+			dl = yellow + dl + reset
+		}
+	}
+	pin := ""
+	if task.Pinned {
+		pin = pinMarker
+	}
+	links := ""
+	for _, remote := range task.Remotes {
+		if remote.URL != "" {
+			links += " " + remoteLabel(remote)
+		}
+	}
+	prefix := fmt.Sprintf("%s%s#%d: %s", strings.Repeat("  ", depth), pin, task.ID, priorityLabel(task.Priority))
+	if task.paused(time.Now()) {
+		status += ", paused"
+	}
+	suffix := fmt.Sprintf("%s [%s]%s%s%s", tagLabels(task.Tags), status, spentLabel(task, time.Now()), dl, links)
+	title := fitTitle(task.Title, visibleLen(prefix+suffix))
+	return prefix + decorate(title) + suffix
+}
+
 // clearTasks removes all tasks
 func clearTasks() []Task {
 	return []Task{}
@@ -106,11 +398,115 @@ func clearTasks() []Task {
 // printUsage shows available commands
 func printUsage() {
 	fmt.Println("Usage:")
+	fmt.Println("  init                                  - Set up a data directory and config")
+	fmt.Println("  config export [file] | import <file>  - Share settings without personal details")
 	fmt.Println("  add \"task name\" [deadline YYYY-MM-DD] - Add a new task with optional deadline")
-	fmt.Println("  list                                  - List all tasks")
-	fmt.Println("  delete <id>                           - Delete a task by ID")
-	fmt.Println("  done <id>                             - Mark a task as done by ID")
+	fmt.Println("      --project <name>                  - Put the new task in a project")
+	fmt.Println("      --priority low|medium|high        - Set the new task's priority")
+	fmt.Println("      --tag <tag>                       - Tag the new task, may be repeated")
+	fmt.Println("      --parent <id|title>               - Add the task as a subtask")
+	fmt.Println("      --repeat daily|weekly|monthly|yearly|30d|\"<cron>\"|\"<RRULE>\"")
+	fmt.Println("                                        - Add the next occurrence when it is done")
+	fmt.Println("      --repeat-from due|done            - Count it from the deadline or the day it's done")
+	fmt.Println("  list [--project name] [--tag tag]     - List all tasks")
+	fmt.Println("      --pending | --done | --overdue    - Only list tasks in that state")
+	fmt.Println("      --sort deadline|id|title|status|priority [--reverse]")
+	fmt.Println("                                        - Order tasks (default priority)")
+	fmt.Println("      --columns countdown               - Show time left until each deadline")
+	fmt.Println("      --full                            - Don't shorten long titles to fit")
+	fmt.Println("      --archived                        - List archived tasks instead")
+	fmt.Println("      --where '<expr>'                  - Only list tasks matching, e.g. '.priority == \"high\"'")
+	fmt.Println("      --saved <name>                    - Only list tasks matching a saved search")
+	fmt.Println("      --select '<expr>'                 - Print one value per task, e.g. 'upper(.title)'")
+	fmt.Println("  due [days] [--full]                   - Show tasks due in the next days (default 7)")
+	fmt.Println("  overdue [--full]                      - Show late tasks and how late they are")
+	fmt.Println("  search <text> [--all] [--full]        - Find tasks by title or notes, best matches first")
+	fmt.Println("  delete <id|title>                     - Delete a task by ID or title")
+	fmt.Println("  done <id|title>                       - Mark a task as done by ID or title")
+	fmt.Println("                                          (parents finish with their last subtask)")
+	fmt.Println("  undone|reopen <id|title>              - Mark a done task as not done")
+	fmt.Println("  start <id|title> [--force]            - Start tracking time on a task")
+	fmt.Println("                                          (--force goes over the wip_limit)")
+	fmt.Println("  stop [id|title]                       - Stop tracking a task, or all of them")
+	fmt.Println("  pomodoro <id> [--work 25m] [--break 5m] [--cycles n] [--notify]")
+	fmt.Println("                                        - Time work periods on a task")
+	fmt.Println("  status                                - Show tasks in progress and their age")
+	fmt.Println("  stats                                 - Show completion and work in progress figures")
+	fmt.Println("  pause <id|title> [--until <date>]     - Hold a recurring task and its reminders")
+	fmt.Println("  resume <id|title>                     - Take a paused task off hold")
+	fmt.Println("  pin|unpin <id|title>                  - Keep a task at the top of lists")
+	fmt.Println("  snooze|postpone <id> <1d|3d|1w|1m>    - Push a task's deadline back")
+	fmt.Println("  edit <id> [--title \"...\"] [--deadline YYYY-MM-DD|none]")
+	fmt.Println("                                        - Change a task's title or deadline")
+	fmt.Println("  priority <id> low|medium|high|none    - Change a task's priority")
+	fmt.Println("  split <id> \"part 1\" \"part 2\" ...      - Break a task into subtasks")
+	fmt.Println("  merge <id> <id>...                    - Combine duplicate tasks into the first")
+	fmt.Println("  focus <id>... | clear                 - Limit list to the given tasks")
+	fmt.Println("  plan today                            - Choose today's tasks interactively")
+	fmt.Println("  today                                 - Show today's plan and what is due")
+	fmt.Println("  week [YYYY-Www]                       - Show what is due each day of a week")
+	fmt.Println("  wrap                                  - Review the day and log a summary")
+	fmt.Println("  standup                               - Print a Markdown standup report")
+	fmt.Println("                                          (tasks tagged +blocked or +waiting are blockers)")
+	fmt.Println("  remind [--before 2h] [--once]         - Notify on the desktop as deadlines near")
+	fmt.Println("      --digest                          - Send the digest of upcoming tasks now")
+	fmt.Println("  report [--since 7d] [--group tag|project]")
+	fmt.Println("                                        - Summarize completed, added and overdue tasks")
+	fmt.Println("  report review [--period month|quarter] [--previous]")
+	fmt.Println("                                        - Print a Markdown review of the period")
+	fmt.Println("  report timesheet [--from 7d|YYYY-MM-DD] [--to YYYY-MM-DD] [--format csv]")
+	fmt.Println("                                        - Break tracked time and completions down by day and tag")
+	fmt.Println("  sync jira --jql \"<query>\" [--instance n] - Import Jira issues as tasks")
+	fmt.Println("  sync gitlab                           - Import GitLab issues and review requests")
+	fmt.Println("  sync mstodo                           - Sync both ways with Microsoft To Do")
+	fmt.Println("  sync vault                            - Sync checklist items in Markdown notes")
+	fmt.Println("  sync caldav [--url u] [--user name]   - Sync both ways with a CalDAV task list")
+	fmt.Println("  sync gcal                             - Put deadlines on Google Calendar")
+	fmt.Println("  sync all                              - Run every configured provider")
+	fmt.Println("  sync <name> [args]                    - Sync through a todo-provider-<name> plugin")
+	fmt.Println("      --debug-http <file>               - Record sanitized HTTP traffic to file")
+	fmt.Println("      --replay-http <file>              - Answer requests from a recording")
+	fmt.Println("  auth login|logout <gitlab|jira|mstodo|gcal|caldav> [--instance n]")
+	fmt.Println("                                        - Keep a provider's token in the system keychain")
+	fmt.Println("  import --format trello <file>         - Import tasks from a Trello board export")
+	fmt.Println("  import --format json <file> [--merge] - Import a JSON export, merging by task UID")
+	fmt.Println("  import --format <plugin> <file>       - Import tasks through a provider plugin")
+	fmt.Println("  export --format <format> [file]       - Export tasks (json, markdown, ics,")
+	fmt.Println("                                          trello, checklist or a plugin)")
+	fmt.Println("      --vtodo                           - With ics, write tasks instead of all-day events")
+	fmt.Println("      --filter \"<terms>\"                - Only export matching tasks")
+	fmt.Println("  show <id|title>                       - Show task details, notes and links")
+	fmt.Println("  note <id|title> [text]                - Add to a task's notes, or print them")
+	fmt.Println("      --replace | --edit                - Replace the notes, or edit them in $EDITOR")
+	fmt.Println("  open <id|title>                       - Open a task's link in the browser")
+	fmt.Println("  listen --socket [path]                - Accept quick-add lines on a unix socket")
+	fmt.Println("  rpc                                   - Serve JSON-RPC on stdio for editor plugins")
+	fmt.Println("  project add|close|list [name]         - Manage projects")
+	fmt.Println("  project set <name> [--priority p] [--tag t] [--no-tags]")
+	fmt.Println("                                        - Set the priority and tags new tasks in it get")
+	fmt.Println("  searches [list]                       - Show saved searches and how many tasks match")
+	fmt.Println("  searches add <name> --where '<expr>' [--notify]")
+	fmt.Println("                                        - Save a search; remind alerts on new matches")
+	fmt.Println("  searches remove <name>                - Delete a saved search")
+	fmt.Println("  scan <dir>                            - Track TODO/FIXME comments as tasks")
+	fmt.Println("  agenda [--print]                      - Show today's agenda, or a printable card")
+	fmt.Println("  reschedule --filter \"<terms>\" --to <date|+3d|next-monday> [--yes]")
+	fmt.Println("                                        - Move deadlines of matching tasks")
+	fmt.Println("  tag add|remove <tag> --filter \"<terms>\"")
+	fmt.Println("                                        - Tag or untag every matching task")
+	fmt.Println("  retag <old> <new>                     - Rename a tag on every task")
+	fmt.Println("  tags [prune | merge <from> <into>]    - List tags with open and done counts")
+	fmt.Println("                                          (prune drops tags only done tasks carry)")
+	fmt.Println("  balance [--week YYYY-Www] [--apply]   - Spread deadlines off overloaded days")
+	fmt.Println("  backup export|import <file[.age]>     - Save or restore all data, .age encrypts")
+	fmt.Println("  verify [--accept]                     - Check tasks.txt against its checksum")
+	fmt.Println("  archive                               - Move done tasks to the archive")
 	fmt.Println("  clear                                 - Delete all tasks")
+	fmt.Println("  journal [n]                           - Show the last n operations (default 20)")
+	fmt.Println("  events [--since id] [--follow]        - Print changes as JSON lines, --follow streams")
+	fmt.Println("  undo [--id <opID>]                    - Revert the last change, or a journal entry")
+	fmt.Println("  history cmd [n]                       - Show the last n commands run (default 20)")
+	fmt.Println("  redo-last [args...]                   - Run the last command again, adding args")
 }
 
 // Colors
@@ -124,8 +520,33 @@ var (
 func main() {
 	// Load existing tasks
 	tasks, err := loadTasks()
-	if err != nil {
+	if errors.Is(err, errChecksum) && len(os.Args) > 1 && os.Args[1] == "backup" {
+		// backup copies the data files as they are and never uses the tasks
+		tasks, err = nil, nil
+	}
+	if errors.Is(err, errChecksum) && len(os.Args) > 1 && os.Args[1] == "verify" {
+		// verify reports on a damaged tasks file, even one it can only partly read
+	} else if err != nil {
 		fmt.Printf("Error loading tasks: %v\n", err)
+		if err == errChecksum {
+			fmt.Println("Restore it with \"backup import <file>\", or keep it as it is with \"verify --accept\"")
+		} else if errors.Is(err, errChecksum) {
+			fmt.Println("Restore it with \"backup import <file>\"")
+		}
+		os.Exit(1)
+	}
+
+	config, err = loadConfig()
+	if err != nil && (len(os.Args) < 2 || os.Args[1] != "init") {
+		// init can still run, since it is how a broken config is replaced
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Accessible {
+		useAccessiblePalette()
+	}
+	if err := parseColumns(config.Columns); err != nil {
+		fmt.Printf("Error loading config: columns: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -136,40 +557,267 @@ func main() {
 	}
 
 	command := os.Args[1]
+	original := copyTasks(tasks)
+	stdin := bufio.NewReader(os.Stdin)
+	// undone is the journal operation reverted by undo
+	undone := 0
 
 	switch command {
 	case "add":
-		if len(os.Args) < 3 {
+		args, flags, err := parseFlags(os.Args[2:], "project", "priority", "tag", "parent", "repeat", "repeat-from")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 1 {
 			fmt.Println("Error: Task title is required")
 			printUsage()
 			os.Exit(1)
 		}
-		title := os.Args[2]
+		priority := priorityNone
+		if flags.has("priority") {
+			if priority, err = parsePriority(flags.get("priority")); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if flags.has("repeat") {
+			if err := checkRepeat(flags.get("repeat")); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if flags.has("repeat-from") {
+			if !flags.has("repeat") {
+				fmt.Println("Error: --repeat-from needs --repeat")
+				os.Exit(1)
+			}
+			if err := checkRepeatFrom(flags.get("repeat-from")); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		title := args[0]
 		var deadline string
-		if len(os.Args) > 3 {
-			deadline = os.Args[3]
+		if len(args) > 1 {
+			deadline = args[1]
+		}
+		parentID := 0
+		if flags.has("parent") {
+			if parentID, err = resolveTarget(tasks, flags.get("parent"), stdin); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if findTask(tasks, parentID) == nil {
+				fmt.Printf("Error: Parent task #%d not found\n", parentID)
+				os.Exit(1)
+			}
+		}
+		var project *Project
+		name := config.DefaultProject
+		if parent := findTask(tasks, parentID); parent != nil && parent.Project != "" {
+			// Subtasks stay in their parent's project
+			name = parent.Project
+		}
+		if flags.has("project") {
+			name = flags.get("project")
+		}
+		if name != "" {
+			if project, err = openProject(name); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
 		}
 		var newID int
 		tasks, newID = addTask(tasks, title, deadline)
+		if project != nil {
+			project.join(findTask(tasks, newID))
+		}
+		if flags.has("priority") {
+			findTask(tasks, newID).Priority = priority
+		}
+		findTask(tasks, newID).Tags = addTags(findTask(tasks, newID).Tags, flags["tag"])
+		findTask(tasks, newID).Repeat = flags.get("repeat")
+		if flags.get("repeat-from") == repeatFromDone {
+			findTask(tasks, newID).RepeatFrom = repeatFromDone
+		}
+		if parentID != 0 {
+			if tasks, err = addSubtask(tasks, newID, parentID); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%sAdded task #%d under #%d:%s %s\n", green, newID, parentID, reset, title)
+			break
+		}
 		fmt.Printf("%sAdded task #%d:%s %s\n", green, newID, reset, title)
 
 	case "list":
-		if len(tasks) == 0 {
+		_, flags, err := parseFlags(os.Args[2:], "project", "tag", "sort", "columns", "select", "where", "saved")
+		if err == nil {
+			err = parseColumns(flags["columns"])
+		}
+		var selects []expr
+		for _, src := range flags["select"] {
+			if err == nil {
+				var e expr
+				e, err = compileExpr(src)
+				selects = append(selects, e)
+			}
+		}
+		var where expr
+		if err == nil && flags.has("where") {
+			where, err = compileExpr(flags.get("where"))
+		}
+		var saved expr
+		if err == nil && flags.has("saved") {
+			saved, err = savedSearchExpr(flags.get("saved"))
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if flags.has("full") {
+			lineWidth = 0
+		}
+		listed := tasks
+		if flags.has("archived") {
+			if listed, err = archivedTasks(tasks); err != nil {
+				fmt.Printf("Error loading archive: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		key := "priority"
+		if flags.has("sort") {
+			key = flags.get("sort")
+		}
+		shown, err := sortTasks(listed, key, flags.has("reverse"))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if flags.has("project") {
+			shown = projectTasks(shown, flags.get("project"))
+		}
+		for _, tag := range flags["tag"] {
+			var tagged []Task
+			for _, task := range shown {
+				if hasTag(task, tag) {
+					tagged = append(tagged, task)
+				}
+			}
+			shown = tagged
+		}
+		var terms []string
+		for _, term := range []string{"pending", "done", "overdue"} {
+			if flags.has(term) {
+				terms = append(terms, term)
+			}
+		}
+		filter, _ := parseFilter(strings.Join(terms, " "), time.Now())
+		shown = pinnedFirst(filter.apply(shown))
+		for _, cond := range []expr{saved, where} {
+			if cond == nil {
+				continue
+			}
+			if shown, err = whereTasks(shown, cond); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if len(selects) > 0 {
+			// Plain lines for scripts: no header, colors or tree
+			if err := printSelected(shown, selects); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			break
+		}
+		if len(shown) == 0 {
 			fmt.Println(yellow + "No tasks found" + reset)
 			break
 		}
+		focus, err := loadFocus()
+		if err != nil {
+			fmt.Printf("Error loading focus: %v\n", err)
+			os.Exit(1)
+		}
+		if focus.active() && !flags.has("archived") {
+			fmt.Println("Tasks (focus):")
+			printTaskTree(focus.filter(shown, time.Now()), 0, 0)
+			break
+		}
 		fmt.Println("Tasks:")
-		for _, task := range tasks {
-			status := red + "Not Done" + reset
-			if task.Done {
-				status = green + "Done" + reset
-			}
-			dl := ""
-			if !task.Deadline.IsZero() {
-				dl = " (Deadline: " + task.Deadline.Format("2006-01-02") + ")"
+		printTaskTree(shown, 0, 0)
+
+	case "search":
+		args, flags, err := parseFlags(os.Args[2:])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 1 {
+			fmt.Println("Error: Search text is required")
+			printUsage()
+			os.Exit(1)
+		}
+		if flags.has("full") {
+			lineWidth = 0
+		}
+		query := strings.Join(args, " ")
+		hits := searchTasks(tasks, query)
+		if len(hits) == 0 {
+			fmt.Printf("%sNo tasks match %q%s\n", yellow, query, reset)
+			break
+		}
+		fmt.Printf("Tasks matching %q:\n", query)
+		shown := hits
+		if len(shown) > searchLimit && !flags.has("all") {
+			shown = shown[:searchLimit]
+		}
+		for _, hit := range shown {
+			fmt.Println(taskLine(hit.Task, 0, func(title string) string {
+				return highlightRunes(title, hit.Runes)
+			}))
+		}
+		if len(shown) < len(hits) {
+			fmt.Printf("%sShowing the best %d of %d matches, use --all to see every one%s\n", yellow, len(shown), len(hits), reset)
+		}
+
+	case "due":
+		args, flags, err := parseFlags(os.Args[2:])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if flags.has("full") {
+			lineWidth = 0
+		}
+		days := 7
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 0 {
+				fmt.Println("Error: Days must be a positive number")
+				os.Exit(1)
 			}
-			fmt.Printf("#%d: %s [%s]%s\n", task.ID, task.Title, status, dl)
+			days = n
 		}
+		due := dueWithin(tasks, days, time.Now())
+		if len(due) == 0 {
+			fmt.Printf("%sNothing due in the next %d days%s\n", green, days, reset)
+			break
+		}
+		printDue(due, time.Now())
+
+	case "overdue":
+		if _, flags, _ := parseFlags(os.Args[2:]); flags.has("full") {
+			lineWidth = 0
+		}
+		late := overdueTasks(tasks, time.Now())
+		if len(late) == 0 {
+			fmt.Println(green + "Nothing is overdue" + reset)
+			break
+		}
+		printOverdue(late, time.Now())
 
 	case "delete":
 		if len(os.Args) < 3 {
@@ -177,9 +825,9 @@ func main() {
 			printUsage()
 			os.Exit(1)
 		}
-		id, err := strconv.Atoi(os.Args[2])
+		id, err := resolveTarget(tasks, os.Args[2], stdin)
 		if err != nil {
-			fmt.Println("Error: ID must be a number")
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
 		var found bool
@@ -196,32 +844,1183 @@ func main() {
 			printUsage()
 			os.Exit(1)
 		}
-		id, err := strconv.Atoi(os.Args[2])
+		id, err := resolveTarget(tasks, os.Args[2], stdin)
 		if err != nil {
-			fmt.Println("Error: ID must be a number")
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
-		var found bool
-		tasks, found = markDone(tasks, id)
-		if !found {
-			fmt.Printf("Error: Task #%d not found\n", id)
+		var done completion
+		tasks, done, err = completeTask(tasks, id, time.Now())
+		if task := findTask(tasks, id); task == nil || !task.Done {
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
+		if done.stopped {
+			fmt.Printf("%sStopped tracking task #%d%s\n", yellow, id, reset)
+		}
 		fmt.Printf("%sMarked task #%d as done%s\n", green, id, reset)
+		for _, parentID := range done.parents {
+			fmt.Printf("%sAll subtasks done, marked task #%d as done%s\n", green, parentID, reset)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if done.nextID != 0 {
+			fmt.Printf("%sNext occurrence is task #%d, due %s%s\n", green, done.nextID, findTask(tasks, done.nextID).Deadline.Format("2006-01-02"), reset)
+		}
 
-	case "clear":
-		tasks = clearTasks()
-		fmt.Println(yellow + "All tasks cleared!" + reset)
+	case "start":
+		args, flags, err := parseFlags(os.Args[2:])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 1 {
+			fmt.Println("Error: Task ID is required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, args[0], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if overWIPLimit(tasks) {
+			if !flags.has("force") {
+				fmt.Printf("Error: WIP limit of %d reached (%d in progress), stop a task or use --force\n", config.WIPLimit, len(trackedTasks(tasks)))
+				os.Exit(1)
+			}
+			fmt.Printf("%sWarning: going over the WIP limit of %d%s\n", yellow, config.WIPLimit, reset)
+		}
+		if tasks, err = startTracking(tasks, id, time.Now()); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sStarted tracking task #%d%s\n", green, id, reset)
 
-	default:
-		printUsage()
-		os.Exit(1)
-	}
+	case "stop":
+		// Without an ID every running session is stopped
+		ids := trackedTasks(tasks)
+		if len(os.Args) > 2 {
+			id, err := resolveTarget(tasks, os.Args[2], stdin)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			ids = []int{id}
+		}
+		if len(ids) == 0 {
+			fmt.Println("Error: No task is being tracked")
+			os.Exit(1)
+		}
+		for _, id := range ids {
+			var session time.Duration
+			var err error
+			if tasks, session, err = stopTracking(tasks, id, time.Now()); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			total := findTask(tasks, id).timeSpent(time.Now())
+			fmt.Printf("%sStopped task #%d after %s, %s in total%s\n", yellow, id, formatSpent(session), formatSpent(total), reset)
+		}
 
-	// Save tasks if modified
-	if command == "add" || command == "delete" || command == "done" || command == "clear" {
-		if err := saveTasks(tasks); err != nil {
-			fmt.Printf("Error saving tasks: %v\n", err)
+	case "pomodoro":
+		args, flags, err := parseFlags(os.Args[2:], "work", "break", "cycles")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 1 {
+			fmt.Println("Error: Task ID is required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, args[0], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		task := findTask(tasks, id)
+		if task == nil {
+			fmt.Printf("Error: Task #%d not found\n", id)
+			os.Exit(1)
+		}
+		lengths := map[string]time.Duration{"work": 25 * time.Minute, "break": 5 * time.Minute}
+		for name := range lengths {
+			if flags.has(name) {
+				if lengths[name], err = time.ParseDuration(flags.get(name)); err != nil || lengths[name] <= 0 {
+					fmt.Printf("Error: Invalid --%s length %q, use e.g. 25m\n", name, flags.get(name))
+					os.Exit(1)
+				}
+			}
+		}
+		cycles := 1
+		if flags.has("cycles") {
+			if cycles, err = strconv.Atoi(flags.get("cycles")); err != nil || cycles < 1 {
+				fmt.Println("Error: --cycles must be a positive number")
+				os.Exit(1)
+			}
+		}
+		if err := pomodoro(*task, lengths["work"], lengths["break"], cycles, flags.has("notify")); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "status":
+		printStatus(tasks, time.Now())
+
+	case "stats":
+		archived, err := archivedTasks(tasks)
+		if err != nil {
+			fmt.Printf("Error loading archive: %v\n", err)
+			os.Exit(1)
+		}
+		printStats(tasks, archived, time.Now())
+
+	case "undone", "reopen":
+		if len(os.Args) < 3 {
+			fmt.Println("Error: Task ID is required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, os.Args[2], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		var found bool
+		tasks, found = markUndone(tasks, id)
+		if !found {
+			fmt.Printf("Error: Task #%d not found\n", id)
+			os.Exit(1)
+		}
+		fmt.Printf("%sReopened task #%d%s\n", yellow, id, reset)
+
+	case "pause":
+		args, flags, err := parseFlags(os.Args[2:], "until")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 1 {
+			fmt.Println("Error: Task ID is required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, args[0], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		var until time.Time
+		if flags.has("until") {
+			date, err := parseDateSpec(flags.get("until"), time.Now())
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			until = date(time.Time{})
+		}
+		if tasks, err = pauseTask(tasks, id, until, time.Now()); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if until.IsZero() {
+			fmt.Printf("%sPaused task #%d until it is resumed%s\n", yellow, id, reset)
+		} else {
+			fmt.Printf("%sPaused task #%d until %s, next due %s%s\n", yellow, id, until.Format("2006-01-02"), findTask(tasks, id).Deadline.Format("2006-01-02"), reset)
+		}
+
+	case "resume":
+		if len(os.Args) < 3 {
+			fmt.Println("Error: Task ID is required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, os.Args[2], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if tasks, err = resumeTask(tasks, id, time.Now()); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sResumed task #%d%s\n", green, id, reset)
+
+	case "pin", "unpin":
+		if len(os.Args) < 3 {
+			fmt.Println("Error: Task ID is required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, os.Args[2], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		var found bool
+		tasks, found = setPinned(tasks, id, command == "pin")
+		if !found {
+			fmt.Printf("Error: Task #%d not found\n", id)
+			os.Exit(1)
+		}
+		if command == "pin" {
+			fmt.Printf("%sPinned task #%d%s\n", green, id, reset)
+		} else {
+			fmt.Printf("%sUnpinned task #%d%s\n", yellow, id, reset)
+		}
+
+	case "note":
+		args, flags, err := parseFlags(os.Args[2:])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 1 {
+			fmt.Println("Error: Task ID is required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, args[0], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		task := findTask(tasks, id)
+		if task == nil {
+			fmt.Printf("Error: Task #%d not found\n", id)
+			os.Exit(1)
+		}
+		text := strings.Join(args[1:], " ")
+		before := task.Notes
+		switch {
+		case flags.has("edit"):
+			if task.Notes, err = editNotes(task.Notes); err != nil {
+				fmt.Printf("Error: Editor failed: %v\n", err)
+				os.Exit(1)
+			}
+		case flags.has("replace"):
+			task.Notes = text
+		case text != "":
+			task.Notes = appendNote(task.Notes, text)
+		default:
+			if task.Notes == "" {
+				fmt.Printf("Task #%d has no notes\n", id)
+			} else {
+				fmt.Println(task.Notes)
+			}
+		}
+		if task.Notes != before {
+			fmt.Printf("%sUpdated the notes of task #%d%s\n", green, id, reset)
+		}
+
+	case "snooze", "postpone":
+		if len(os.Args) < 4 {
+			fmt.Println("Error: Task ID and duration are required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, os.Args[2], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		task := findTask(tasks, id)
+		if task == nil {
+			fmt.Printf("Error: Task #%d not found\n", id)
+			os.Exit(1)
+		}
+		spec := "+" + strings.TrimPrefix(os.Args[3], "+")
+		if years, months, days, err := parseOffset(spec); err == nil && (years < 0 || months < 0 || days < 0 || years+months+days == 0) {
+			fmt.Printf("Error: snooze needs a positive duration such as 1d, 3d, 1w or 1m, got %q\n", os.Args[3])
+			os.Exit(1)
+		}
+		later, err := parseDateSpec(spec, time.Now())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		task.Deadline = later(task.Deadline)
+		fmt.Printf("%sSnoozed task #%d until %s%s\n", yellow, id, task.Deadline.Format("2006-01-02"), reset)
+
+	case "edit":
+		args, flags, err := parseFlags(os.Args[2:], "title", "deadline")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 1 || (!flags.has("title") && !flags.has("deadline")) {
+			fmt.Println("Error: Task ID and --title or --deadline are required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, args[0], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		var title, deadline *string
+		if flags.has("title") {
+			value := flags.get("title")
+			title = &value
+		}
+		if flags.has("deadline") {
+			value := flags.get("deadline")
+			deadline = &value
+		}
+		if tasks, err = editTask(tasks, id, title, deadline); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sUpdated task #%d%s\n", green, id, reset)
+
+	case "priority":
+		if len(os.Args) < 4 {
+			fmt.Println("Error: Task ID and priority are required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, os.Args[2], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		task := findTask(tasks, id)
+		if task == nil {
+			fmt.Printf("Error: Task #%d not found\n", id)
+			os.Exit(1)
+		}
+		if task.Priority, err = parsePriority(os.Args[3]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sSet priority of task #%d to %s%s\n", green, id, priorityNames[task.Priority], reset)
+
+	case "split":
+		if len(os.Args) < 5 {
+			fmt.Println("Error: Task ID and at least two parts are required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, os.Args[2], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		var ids []int
+		var found bool
+		tasks, ids, found = splitTask(tasks, id, os.Args[3:])
+		if !found {
+			fmt.Printf("Error: Task #%d not found\n", id)
+			os.Exit(1)
+		}
+		fmt.Printf("%sSplit task #%d into %d subtasks%s\n", green, id, len(ids), reset)
+
+	case "merge":
+		if len(os.Args) < 4 {
+			fmt.Println("Error: At least two task IDs are required")
+			printUsage()
+			os.Exit(1)
+		}
+		var ids []int
+		for _, arg := range os.Args[2:] {
+			id, err := resolveTarget(tasks, arg, stdin)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			ids = append(ids, id)
+		}
+		var merged int
+		tasks, merged, err = mergeTasks(tasks, ids)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sMerged %d tasks into #%d%s\n", green, merged+1, ids[0], reset)
+
+	case "focus":
+		focus, err := loadFocus()
+		if err != nil {
+			fmt.Printf("Error loading focus: %v\n", err)
+			os.Exit(1)
+		}
+		if len(os.Args) < 3 {
+			if !focus.active() {
+				fmt.Println(yellow + "Focus is not active" + reset)
+				break
+			}
+			fmt.Printf("Focusing on %d tasks since %s\n", len(focus.IDs), focus.Since.Format("15:04"))
+			printTaskTree(focus.filter(tasks, time.Now()), 0, 0)
+			break
+		}
+		if os.Args[2] == "clear" {
+			if err := saveFocus(Focus{}); err != nil {
+				fmt.Printf("Error saving focus: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(yellow + "Focus cleared" + reset)
+			break
+		}
+		focus = Focus{Since: time.Now()}
+		for _, arg := range os.Args[2:] {
+			id, err := resolveTarget(tasks, arg, stdin)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if findTask(tasks, id) == nil {
+				fmt.Printf("Error: Task #%d not found\n", id)
+				os.Exit(1)
+			}
+			focus.IDs = append(focus.IDs, id)
+		}
+		if err := saveFocus(focus); err != nil {
+			fmt.Printf("Error saving focus: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sFocusing on %d tasks%s\n", green, len(focus.IDs), reset)
+
+	case "plan":
+		if len(os.Args) < 3 || os.Args[2] != "today" {
+			fmt.Println("Error: Only \"plan today\" is supported")
+			printUsage()
+			os.Exit(1)
+		}
+		var plan Plan
+		tasks, plan = planDay(tasks, os.Stdin, time.Now())
+		if err := savePlan(plan); err != nil {
+			fmt.Printf("Error saving plan: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sPlanned %d tasks for today%s\n", green, len(plan.IDs), reset)
+		printPlan(tasks, plan)
+
+	case "today":
+		plan, err := loadPlan()
+		if err != nil {
+			fmt.Printf("Error loading plan: %v\n", err)
+			os.Exit(1)
+		}
+		if plan.isFor(time.Now()) && len(plan.IDs) > 0 {
+			fmt.Println("Plan:")
+			printPlan(tasks, plan)
+		} else {
+			fmt.Println(yellow + "No plan for today, run \"plan today\"" + reset)
+		}
+		fmt.Println()
+		printDays(tasks, logicalDate(time.Now()), 1, time.Now())
+
+	case "week":
+		start := weekStart(logicalDate(time.Now()))
+		if len(os.Args) > 2 {
+			if start, err = parseISOWeek(os.Args[2]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		printDays(tasks, start, 7, time.Now())
+
+	case "wrap":
+		ops, err := loadJournal()
+		if err != nil {
+			fmt.Printf("Error loading journal: %v\n", err)
+			os.Exit(1)
+		}
+		plan, err := loadPlan()
+		if err != nil {
+			fmt.Printf("Error loading plan: %v\n", err)
+			os.Exit(1)
+		}
+		var summary string
+		tasks, summary = wrapDay(tasks, ops, plan, os.Stdin, time.Now())
+		if err := appendDayLog(summary); err != nil {
+			fmt.Printf("Error writing day log: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(green + "Day logged to daylog.md" + reset)
+
+	case "standup":
+		ops, err := loadJournal()
+		if err != nil {
+			fmt.Printf("Error loading journal: %v\n", err)
+			os.Exit(1)
+		}
+		plan, err := loadPlan()
+		if err != nil {
+			fmt.Printf("Error loading plan: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(standupReport(tasks, ops, plan, time.Now()))
+
+	case "remind":
+		_, flags, err := parseFlags(os.Args[2:], "before")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		lead, err := remindLead(flags.get("before"))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if flags.has("digest") {
+			// Send a digest now, e.g. from a cron job at the digest time
+			err = sendDigest(digest(tasks, lead, time.Now()))
+		} else {
+			err = remind(lead, flags.has("once"))
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "report":
+		args, flags, err := parseFlags(os.Args[2:], "period", "since", "group", "from", "to", "format")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) > 0 && args[0] != "review" && args[0] != "timesheet" {
+			fmt.Printf("Error: Unknown report %q\n", args[0])
+			printUsage()
+			os.Exit(1)
+		}
+		if len(args) > 0 && args[0] == "timesheet" {
+			now := time.Now()
+			from, to := logicalDate(now).AddDate(0, 0, -6), logicalDate(now)
+			for _, name := range []string{"from", "to"} {
+				if !flags.has(name) {
+					continue
+				}
+				date, err := reportStart(flags.get(name), now)
+				if err != nil {
+					fmt.Printf("Error: invalid --%s %q, use e.g. 7d or YYYY-MM-DD\n", name, flags.get(name))
+					os.Exit(1)
+				}
+				if name == "from" {
+					from = date
+				} else {
+					to = date
+				}
+			}
+			if to.Before(from) {
+				fmt.Println("Error: --to is before --from")
+				os.Exit(1)
+			}
+			format := flags.get("format")
+			if format != "" && format != "text" && format != "csv" {
+				fmt.Println("Error: --format must be text or csv")
+				os.Exit(1)
+			}
+			archived, err := archivedTasks(tasks)
+			if err != nil {
+				fmt.Printf("Error loading archive: %v\n", err)
+				os.Exit(1)
+			}
+			sheet, total := timesheet(append(append([]Task(nil), tasks...), archived...), from, to, now)
+			if format == "csv" {
+				os.Stdout.Write(timesheetCSV(sheet))
+			} else {
+				fmt.Print(timesheetText(sheet, total, from, to))
+			}
+			break
+		}
+		if len(args) == 0 {
+			since := "7d"
+			if flags.has("since") {
+				since = flags.get("since")
+			}
+			from, err := reportStart(since, time.Now())
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			group := flags.get("group")
+			if group != "" && group != "tag" && group != "project" {
+				fmt.Println("Error: --group must be tag or project")
+				os.Exit(1)
+			}
+			ops, err := loadJournal()
+			if err != nil {
+				fmt.Printf("Error loading journal: %v\n", err)
+				os.Exit(1)
+			}
+			archived, err := archivedTasks(tasks)
+			if err != nil {
+				fmt.Printf("Error loading archive: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(summaryReport(tasks, archived, ops, from, time.Now(), group))
+			break
+		}
+		period := "month"
+		if flags.has("period") {
+			period = flags.get("period")
+		}
+		from, to, err := reviewPeriod(period, flags.has("previous"), time.Now())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		ops, err := loadJournal()
+		if err != nil {
+			fmt.Printf("Error loading journal: %v\n", err)
+			os.Exit(1)
+		}
+		archived, err := archivedTasks(tasks)
+		if err != nil {
+			fmt.Printf("Error loading archive: %v\n", err)
+			os.Exit(1)
+		}
+		projects, err := loadProjects()
+		if err != nil {
+			fmt.Printf("Error loading projects: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(reviewReport(tasks, archived, ops, projects, period, from, to, time.Now()))
+
+	case "sync":
+		args, finish, err := setupHTTPDebug(os.Args[2:])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		tasks, err = syncCommand(tasks, args)
+		if closeErr := finish(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Printf("Error: Sync failed: %v\n", err)
+			// Keep the links to remote items made before the failure, or
+			// the next sync would create them again
+			if tasksChanged(original, tasks) {
+				if err := saveTasks(tasks); err != nil {
+					fmt.Printf("Error saving tasks: %v\n", err)
+				} else if err := recordOperation(command, original, tasks); err != nil {
+					fmt.Printf("Error writing journal: %v\n", err)
+				}
+			}
+			os.Exit(1)
+		}
+
+	case "import":
+		args, flags, err := parseFlags(os.Args[2:], "format")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 1 {
+			fmt.Println("Error: File to import is required")
+			printUsage()
+			os.Exit(1)
+		}
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+		var added, updated int
+		switch flags.get("format") {
+		case "trello":
+			tasks, added, updated, err = importTrello(tasks, data)
+		case "json":
+			tasks, added, updated, err = importJSON(tasks, data, flags.has("merge"))
+		default:
+			if findPlugin(flags.get("format")) != "" {
+				tasks, added, updated, err = importPlugin(tasks, flags.get("format"), data)
+			} else {
+				err = fmt.Errorf("unsupported format %q", flags.get("format"))
+			}
+		}
+		if err != nil {
+			fmt.Printf("Error: Import failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sImported %d new and %d updated tasks%s\n", green, added, updated, reset)
+
+	case "export":
+		args, flags, err := parseFlags(os.Args[2:], "format", "filter")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		filter, err := parseFilter(flags.get("filter"), time.Now())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		exported := filter.apply(tasks)
+		var data []byte
+		switch flags.get("format") {
+		case "trello":
+			data, err = exportTrello(exported)
+		case "checklist":
+			data = exportChecklist(exported)
+		case "markdown", "md":
+			data = exportMarkdown(exported)
+		case "ics":
+			data = exportICS(exported, flags.has("vtodo"), time.Now())
+		case "json":
+			data, err = exportJSON(exported)
+		default:
+			if findPlugin(flags.get("format")) != "" {
+				data, err = exportPlugin(exported, flags.get("format"))
+			} else {
+				err = fmt.Errorf("unsupported format %q", flags.get("format"))
+			}
+		}
+		if err != nil {
+			fmt.Printf("Error: Export failed: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) > 0 {
+			if err := os.WriteFile(args[0], data, 0644); err != nil {
+				fmt.Printf("Error writing %s: %v\n", args[0], err)
+				os.Exit(1)
+			}
+			fmt.Printf("%sExported %d tasks to %s%s\n", green, len(exported), args[0], reset)
+			break
+		}
+		fmt.Println(string(data))
+
+	case "show", "open":
+		if len(os.Args) < 3 {
+			fmt.Println("Error: Task ID is required")
+			printUsage()
+			os.Exit(1)
+		}
+		id, err := resolveTarget(tasks, os.Args[2], stdin)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		task := findTask(tasks, id)
+		if task == nil {
+			fmt.Printf("Error: Task #%d not found\n", id)
+			os.Exit(1)
+		}
+		if command == "show" {
+			printTaskDetails(tasks, *task)
+			break
+		}
+		link := taskLink(*task)
+		if link == "" {
+			fmt.Printf("Error: Task #%d has no link\n", id)
+			os.Exit(1)
+		}
+		if err := openURL(link); err != nil {
+			fmt.Printf("Error: Cannot open %s: %v\n", link, err)
+			os.Exit(1)
+		}
+		fmt.Println("Opened " + link)
+
+	case "listen":
+		args, flags, err := parseFlags(os.Args[2:])
+		if err != nil || !flags.has("socket") {
+			fmt.Println("Error: Only \"listen --socket\" is supported")
+			printUsage()
+			os.Exit(1)
+		}
+		path := dataPath("todo.sock")
+		if len(args) > 0 {
+			path = args[0]
+		}
+		if err := listen(path); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "rpc":
+		if err := serveRPC(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "project":
+		tasks, err = projectCommand(tasks, os.Args[2:])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "searches":
+		if err := searchesCommand(tasks, os.Args[2:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "scan":
+		dir := "."
+		if len(os.Args) > 2 {
+			dir = os.Args[2]
+		}
+		tasks, err = scanCode(tasks, dir)
+		if err != nil {
+			fmt.Printf("Error: Scan failed: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "agenda":
+		_, flags, err := parseFlags(os.Args[2:])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		plan, err := loadPlan()
+		if err != nil {
+			fmt.Printf("Error loading plan: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(agendaCard(tasks, plan, time.Now(), flags.has("print")))
+
+	case "reschedule":
+		_, flags, err := parseFlags(os.Args[2:], "filter", "to")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if flags.get("filter") == "" || flags.get("to") == "" {
+			fmt.Println("Error: --filter and --to are required")
+			printUsage()
+			os.Exit(1)
+		}
+		tasks, err = rescheduleTasks(tasks, flags.get("filter"), flags.get("to"), flags.has("yes"), os.Stdin, time.Now())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "balance":
+		_, flags, err := parseFlags(os.Args[2:], "week")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		tasks, err = balanceCommand(tasks, flags.get("week"), flags.has("apply"), os.Stdin, time.Now())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "tag":
+		args, flags, err := parseFlags(os.Args[2:], "filter")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 2 || (args[0] != "add" && args[0] != "remove") || flags.get("filter") == "" {
+			fmt.Println("Error: Use tag add|remove <tag> --filter \"<terms>\"")
+			printUsage()
+			os.Exit(1)
+		}
+		var count int
+		tasks, count, err = tagTasks(tasks, args[0], args[1], flags.get("filter"), time.Now())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		verb := "Tagged"
+		if args[0] == "remove" {
+			verb = "Untagged"
+		}
+		fmt.Printf("%s%s %d tasks with +%s%s\n", green, verb, count, strings.TrimPrefix(args[1], "+"), reset)
+
+	case "retag":
+		if len(os.Args) < 4 {
+			fmt.Println("Error: Old and new tag names are required")
+			printUsage()
+			os.Exit(1)
+		}
+		var count int
+		tasks, count, err = retagTasks(tasks, os.Args[2], os.Args[3])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sRetagged %d tasks from +%s to +%s%s\n", green, count, strings.TrimPrefix(os.Args[2], "+"), strings.TrimPrefix(os.Args[3], "+"), reset)
+
+	case "tags":
+		switch {
+		case len(os.Args) < 3:
+			printTags(countTags(tasks))
+		case os.Args[2] == "prune":
+			var pruned []string
+			tasks, pruned = pruneTags(tasks)
+			if len(pruned) == 0 {
+				fmt.Println(yellow + "Every tag is on an open task" + reset)
+				break
+			}
+			fmt.Printf("%sPruned %d tags no open task uses:%s %s\n", green, len(pruned), reset, strings.TrimSpace(tagLabels(pruned)))
+		case os.Args[2] == "merge" && len(os.Args) == 5:
+			var count int
+			tasks, count, err = retagTasks(tasks, os.Args[3], os.Args[4])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%sMerged +%s into +%s on %d tasks%s\n", green, strings.TrimPrefix(os.Args[3], "+"), strings.TrimPrefix(os.Args[4], "+"), count, reset)
+		default:
+			fmt.Println("Error: Use tags, tags prune or tags merge <from> <into>")
+			printUsage()
+			os.Exit(1)
+		}
+
+	case "archive":
+		var count int
+		tasks, count, err = archiveDone(tasks)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sArchived %d done tasks%s\n", green, count, reset)
+
+	case "clear":
+		tasks = clearTasks()
+		fmt.Println(yellow + "All tasks cleared!" + reset)
+
+	case "journal":
+		limit := 20
+		if len(os.Args) > 2 {
+			n, err := strconv.Atoi(os.Args[2])
+			if err != nil {
+				fmt.Println("Error: Count must be a number")
+				os.Exit(1)
+			}
+			limit = n
+		}
+		ops, err := loadJournal()
+		if err != nil {
+			fmt.Printf("Error loading journal: %v\n", err)
+			os.Exit(1)
+		}
+		printJournal(ops, limit)
+
+	case "events":
+		_, flags, err := parseFlags(os.Args[2:], "since")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		ops, err := loadJournal()
+		if err != nil {
+			fmt.Printf("Error loading journal: %v\n", err)
+			os.Exit(1)
+		}
+		// Following starts from now unless --since asks for earlier events
+		since := 0
+		if flags.has("since") {
+			if since, err = strconv.Atoi(flags.get("since")); err != nil {
+				fmt.Println("Error: --since must be an operation ID")
+				os.Exit(1)
+			}
+		} else if flags.has("follow") && len(ops) > 0 {
+			since = ops[len(ops)-1].ID
+		}
+		if since, err = printEvents(ops, since); err == nil && flags.has("follow") {
+			err = followEvents(since)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "undo":
+		_, flags, err := parseFlags(os.Args[2:], "id")
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		ops, err := loadJournal()
+		if err != nil {
+			fmt.Printf("Error loading journal: %v\n", err)
+			os.Exit(1)
+		}
+		var op *Operation
+		if flags.has("id") {
+			opID, err := strconv.Atoi(flags.get("id"))
+			if err != nil {
+				fmt.Println("Error: ID must be a number")
+				os.Exit(1)
+			}
+			for i := range ops {
+				if ops[i].ID == opID {
+					op = &ops[i]
+				}
+			}
+			if op == nil {
+				fmt.Printf("Error: Operation %d not found\n", opID)
+				os.Exit(1)
+			}
+		} else if op = lastUndoable(ops); op == nil {
+			fmt.Println(yellow + "Nothing to undo" + reset)
+			break
+		}
+		tasks, err = revertOperation(tasks, *op)
+		if err != nil {
+			fmt.Printf("Error: Cannot undo: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%sUndid:%s %s\n", yellow, reset, op.describe())
+		undone = op.ID
+
+	case "backup":
+		args, flags, err := parseFlags(os.Args[2:])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) < 2 || (args[0] != "export" && args[0] != "import") {
+			fmt.Println("Error: Use \"backup export <file>\" or \"backup import <file>\"")
+			printUsage()
+			os.Exit(1)
+		}
+		count, verb := 0, "Backed up"
+		if args[0] == "export" {
+			count, err = exportBackup(args[1])
+		} else {
+			count, err = importBackup(args[1], flags.has("yes"), stdin)
+			verb = "Restored"
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s%s %d files%s\n", green, verb, count, reset)
+
+	case "verify":
+		_, flags, err := parseFlags(os.Args[2:])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		data, err := os.ReadFile(dataPath("tasks.txt"))
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if flags.has("accept") {
+			if err := json.Unmarshal(data, &[]Task{}); err != nil {
+				fmt.Printf("Error: tasks.txt cannot be read, restore it with \"backup import <file>\": %v\n", err)
+				os.Exit(1)
+			}
+			if err := writeChecksum(data); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%sAccepted tasks.txt as it is (%d tasks)%s\n", yellow, len(tasks), reset)
+			break
+		}
+		if err := verifyChecksum(data); err != nil {
+			fmt.Printf("%s%v%s\n", red, err, reset)
+			os.Exit(1)
+		}
+		fmt.Printf("%stasks.txt is intact (%d tasks)%s\n", green, len(tasks), reset)
+
+	case "config":
+		args, flags, err := parseFlags(os.Args[2:])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		switch {
+		case len(args) > 0 && args[0] == "export":
+			path := ""
+			if len(args) > 1 {
+				path = args[1]
+			}
+			err = exportConfig(config, path)
+			if err == nil && path != "" {
+				fmt.Printf("%sExported config to %s%s\n", green, path, reset)
+			}
+		case len(args) > 1 && args[0] == "import":
+			err = importConfig(config, args[1], flags.has("yes"), stdin)
+			if err == nil {
+				fmt.Printf("%sImported config from %s%s\n", green, args[1], reset)
+			}
+		default:
+			fmt.Println("Error: Use \"config export [file]\" or \"config import <file>\"")
+			printUsage()
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "auth":
+		args, flags, err := parseFlags(os.Args[2:], "instance")
+		if err == nil {
+			err = authCommand(args, flags.get("instance"), stdin)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "init":
+		if err := initWizard(stdin); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "history":
+		if len(os.Args) < 3 || os.Args[2] != "cmd" {
+			fmt.Println("Error: Only \"history cmd\" is supported")
+			printUsage()
+			os.Exit(1)
+		}
+		limit := 20
+		if len(os.Args) > 3 {
+			n, err := strconv.Atoi(os.Args[3])
+			if err != nil {
+				fmt.Println("Error: Count must be a number")
+				os.Exit(1)
+			}
+			limit = n
+		}
+		history, err := loadHistory()
+		if err != nil {
+			fmt.Printf("Error loading history: %v\n", err)
+			os.Exit(1)
+		}
+		printHistory(history, limit)
+
+	case "redo-last":
+		if err := redoLast(os.Args[2:]); err != nil {
+			// A failed rerun has already reported its own error
+			if _, failed := err.(*exec.ExitError); !failed {
+				fmt.Printf("Error: %v\n", err)
+			}
+			os.Exit(1)
+		}
+
+	default:
+		printUsage()
+		os.Exit(1)
+	}
+
+	// Save tasks and journal the change if anything was modified
+	if tasksChanged(original, tasks) {
+		if err := saveTasks(tasks); err != nil {
+			fmt.Printf("Error saving tasks: %v\n", err)
+			os.Exit(1)
+		}
+		if undone != 0 {
+			err = recordUndo(undone, original, tasks)
+		} else {
+			err = recordOperation(command, original, tasks)
+		}
+		if err != nil {
+			fmt.Printf("Error writing journal: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if command != "history" && command != "redo-last" {
+		if err := recordCommand(os.Args[1:]); err != nil {
+			fmt.Printf("Error writing history: %v\n", err)
 			os.Exit(1)
 		}
 	}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// day parses a YYYY-MM-DD date at local midnight
+func day(t *testing.T, s string) time.Time {
+	t.Helper()
+	date, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return date
+}
+
+func TestMergeTasks(t *testing.T) {
+	start := day(t, "2026-03-01").Add(9 * time.Hour)
+	tasks := []Task{
+		{ID: 1, Title: "Renew passport", Deadline: day(t, "2026-05-01"), Priority: 1,
+			Tags: []string{"admin"}, Notes: "Photos are in the drawer", CreatedAt: day(t, "2026-02-10"),
+			Remotes:  []Remote{{Source: "jira:work", ID: "ADM-1"}},
+			Sessions: []Session{{Start: start, End: start.Add(time.Hour)}}, Pomodoros: 1},
+		{ID: 2, Title: "renew passport", Deadline: day(t, "2026-04-15"), Priority: 3,
+			Tags: []string{"Admin", "travel"}, Notes: "Form is online", CreatedAt: day(t, "2026-01-20"),
+			Project: "travel", Remotes: []Remote{{Source: "gitlab", ID: "issue:7"}},
+			Sessions: []Session{{Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour)}}, Pomodoros: 2},
+		{ID: 3, Title: "Book photo appointment", ParentID: 2},
+		{ID: 4, Title: "Renew passport", Done: true},
+	}
+	// Repeated and duplicate IDs are merged once
+	tasks, merged, err := mergeTasks(tasks, []int{1, 2, 2, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged != 2 || len(tasks) != 2 {
+		t.Fatalf("merged %d tasks leaving %d, want 2 leaving 2", merged, len(tasks))
+	}
+	keep := findTask(tasks, 1)
+	if got := keep.Deadline.Format("2006-01-02"); got != "2026-04-15" {
+		t.Errorf("deadline %s, want the nearest, 2026-04-15", got)
+	}
+	if keep.Priority != 3 {
+		t.Errorf("priority %d, want the highest, 3", keep.Priority)
+	}
+	if !keep.CreatedAt.Equal(day(t, "2026-01-20")) {
+		t.Errorf("created %v, want the earliest", keep.CreatedAt)
+	}
+	if keep.Done {
+		t.Error("merged task is done although #1 and #2 were open")
+	}
+	if keep.Project != "travel" {
+		t.Errorf("project %q, want travel", keep.Project)
+	}
+	if want := []string{"admin", "travel"}; !reflect.DeepEqual(keep.Tags, want) {
+		t.Errorf("tags %v, want %v", keep.Tags, want)
+	}
+	if want := "Photos are in the drawer\n\nForm is online"; keep.Notes != want {
+		t.Errorf("notes %q, want %q", keep.Notes, want)
+	}
+	if len(keep.Remotes) != 2 || len(keep.Sessions) != 2 || keep.Pomodoros != 3 {
+		t.Errorf("got %d remotes, %d sessions and %d pomodoros, want 2, 2 and 3",
+			len(keep.Remotes), len(keep.Sessions), keep.Pomodoros)
+	}
+	if sub := findTask(tasks, 3); sub == nil || sub.ParentID != 1 {
+		t.Errorf("subtask %+v did not move to the kept task", sub)
+	}
+}
+
+func TestMergeTasksErrors(t *testing.T) {
+	tasks := []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "b"}}
+	for _, ids := range [][]int{{1, 1}, {1, 2, 1}, {1, 3}, {3, 1}} {
+		got, _, err := mergeTasks(append([]Task(nil), tasks...), ids)
+		if err == nil {
+			t.Errorf("mergeTasks(%v) succeeded, want an error", ids)
+		}
+		if len(got) != 2 {
+			t.Errorf("mergeTasks(%v) removed tasks despite the error", ids)
+		}
+	}
+}
+
+func TestMergeTasksIntoSubtask(t *testing.T) {
+	// Merging a parent into its own subtask leaves no cycle behind
+	tasks := []Task{
+		{ID: 1, Title: "Plan trip", ParentID: 5},
+		{ID: 2, Title: "Book hotel", ParentID: 1},
+		{ID: 5, Title: "Holidays"},
+	}
+	tasks, _, err := mergeTasks(tasks, []int{2, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keep := findTask(tasks, 2); keep.ParentID != 5 {
+		t.Errorf("kept task has parent %d, want 5", keep.ParentID)
+	}
+}
+
+func TestSplitTask(t *testing.T) {
+	t.Setenv("TODO_DIR", t.TempDir())
+	tasks := []Task{{ID: 1, Title: "Move house", Deadline: day(t, "2026-06-01"),
+		Tags: []string{"home"}, Project: "move", Priority: 2}}
+	tasks, ids, ok := splitTask(tasks, 1, []string{"Pack boxes", "Hire van"})
+	if !ok {
+		t.Fatal("splitTask did not find the task")
+	}
+	if !reflect.DeepEqual(ids, []int{2, 3}) {
+		t.Fatalf("new IDs %v, want [2 3]", ids)
+	}
+	for i, title := range []string{"Pack boxes", "Hire van"} {
+		sub := findTask(tasks, ids[i])
+		if sub.Title != title || sub.ParentID != 1 || !sub.Deadline.Equal(tasks[0].Deadline) ||
+			sub.Project != "move" || sub.Priority != 2 || !reflect.DeepEqual(sub.Tags, []string{"home"}) {
+			t.Errorf("subtask %+v does not carry over the parent's fields", *sub)
+		}
+	}
+	// The subtasks must not share the parent's tag slice
+	findTask(tasks, 2).Tags[0] = "boxes"
+	if tasks[0].Tags[0] != "home" {
+		t.Error("changing a subtask's tags changed the parent's")
+	}
+	if _, _, ok := splitTask(tasks, 9, []string{"x"}); ok {
+		t.Error("splitTask of a missing task succeeded")
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Terminal styles for Markdown in notes. Each is closed with its own
+// reset so styles can nest inside one another and inside colors.
+const (
+	mdBold      = "\033[1m"
+	mdBoldEnd   = "\033[22m"
+	mdItalic    = "\033[3m"
+	mdItalicEnd = "\033[23m"
+	mdUnderline = "\033[4m"
+	mdUnderEnd  = "\033[24m"
+	mdCode      = "\033[36m"
+	mdCodeEnd   = "\033[39m"
+	mdDim       = "\033[2m"
+	mdDimEnd    = "\033[22m"
+)
+
+// mdRuleWidth is how wide a "---" rule is drawn
+const mdRuleWidth = 40
+
+var (
+	// mdHeading is an ATX heading such as "## Steps"
+	mdHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	// mdBullet is an unordered list item, keeping its indent
+	mdBullet = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	// mdNumbered is an ordered list item such as "2. Call"
+	mdNumbered = regexp.MustCompile(`^(\s*)(\d{1,9}[.)])\s+(.*)$`)
+	// mdRule is a thematic break such as "---" or "* * *"
+	mdRule = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	// mdLink is an inline link such as "[docs](https://example.com)"
+	mdLink = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// renderMarkdown styles notes written in Markdown for the terminal:
+// headings and bold text are bold, list markers become bullets, code is
+// colored and links are clickable where the terminal allows it, or
+// followed by their URL. It returns one string per line.
+func renderMarkdown(text string) []string {
+	var lines []string
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			// The fence lines themselves are not shown
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			lines = append(lines, mdCode+line+mdCodeEnd)
+			continue
+		}
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			style, end := mdBold, mdBoldEnd
+			if len(m[1]) == 1 {
+				style, end = mdBold+mdUnderline, mdUnderEnd+mdBoldEnd
+			}
+			lines = append(lines, style+renderInline(m[2])+end)
+			continue
+		}
+		if mdRule.MatchString(line) {
+			lines = append(lines, mdDim+strings.Repeat("─", mdRuleWidth)+mdDimEnd)
+			continue
+		}
+		if m := mdBullet.FindStringSubmatch(line); m != nil {
+			lines = append(lines, m[1]+"• "+renderInline(m[2]))
+			continue
+		}
+		if m := mdNumbered.FindStringSubmatch(line); m != nil {
+			lines = append(lines, m[1]+m[2]+" "+renderInline(m[3]))
+			continue
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			quote := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			lines = append(lines, mdDim+"│ "+mdDimEnd+mdItalic+renderInline(quote)+mdItalicEnd)
+			continue
+		}
+		lines = append(lines, renderInline(line))
+	}
+	return lines
+}
+
+// renderInline styles the code spans, emphasis and links within a line
+func renderInline(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_[]()#+-.!>", rune(rest[1])):
+			// An escaped character is shown as it is
+			out.WriteByte(rest[1])
+			i += 2
+			continue
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				out.WriteString(mdCode + rest[1:1+end] + mdCodeEnd)
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				out.WriteString(mdBold + renderInline(rest[2:2+end]) + mdBoldEnd)
+				i += end + 4
+				continue
+			}
+		case rest[0] == '*' || rest[0] == '_':
+			// Underscores inside words, as in snake_case, are not emphasis
+			before, _ := utf8.DecodeLastRuneInString(text[:i])
+			if rest[0] == '_' && i > 0 && (unicode.IsLetter(before) || unicode.IsDigit(before)) {
+				break
+			}
+			if end := strings.IndexByte(rest[1:], rest[0]); end > 0 && rest[1] != ' ' {
+				out.WriteString(mdItalic + renderInline(rest[1:1+end]) + mdItalicEnd)
+				i += end + 2
+				continue
+			}
+		case rest[0] == '[':
+			if m := mdLink.FindStringSubmatch(rest); m != nil {
+				out.WriteString(markdownLink(renderInline(m[1]), m[2]))
+				i += len(m[0])
+				continue
+			}
+		case strings.HasPrefix(rest, "http"):
+			if loc := urlPattern.FindStringIndex(rest); loc != nil && loc[0] == 0 {
+				out.WriteString(linkURLs(rest[:loc[1]]))
+				i += loc[1]
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(rest)
+		out.WriteString(rest[:size])
+		i += size
+	}
+	return out.String()
+}
+
+// markdownLink renders a link's text underlined, clickable when the
+// terminal supports it and otherwise followed by the URL
+func markdownLink(text, target string) string {
+	if hyperlinks {
+		return hyperlink(mdUnderline+text+mdUnderEnd, target)
+	}
+	return mdUnderline + text + mdUnderEnd + " (" + target + ")"
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	saved := hyperlinks
+	hyperlinks = false
+	defer func() { hyperlinks = saved }()
+
+	notes := "# Plan\n## Steps\n- call **Anna** about `v2`\n  * then *write* it up\n3. upload\n> check first\n```\n**raw** `code`\n```\n---\nplain"
+	want := []string{
+		mdBold + mdUnderline + "Plan" + mdUnderEnd + mdBoldEnd,
+		mdBold + "Steps" + mdBoldEnd,
+		"• call " + mdBold + "Anna" + mdBoldEnd + " about " + mdCode + "v2" + mdCodeEnd,
+		"  • then " + mdItalic + "write" + mdItalicEnd + " it up",
+		"3. upload",
+		mdDim + "│ " + mdDimEnd + mdItalic + "check first" + mdItalicEnd,
+		mdCode + "**raw** `code`" + mdCodeEnd,
+		mdDim + "────────────────────────────────────────" + mdDimEnd,
+		"plain",
+	}
+	if got := renderMarkdown(notes); !reflect.DeepEqual(got, want) {
+		t.Errorf("renderMarkdown =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestRenderInline(t *testing.T) {
+	saved := hyperlinks
+	hyperlinks = false
+	defer func() { hyperlinks = saved }()
+
+	tests := []struct{ text, want string }{
+		{"see [the wiki](https://wiki.example.com)", "see " + mdUnderline + "the wiki" + mdUnderEnd + " (https://wiki.example.com)"},
+		{"__bold__ and _italic_", mdBold + "bold" + mdBoldEnd + " and " + mdItalic + "italic" + mdItalicEnd},
+		{"keep snake_case_names", "keep snake_case_names"},
+		{`\*not italic\*`, "*not italic*"},
+		{"2 * 3 * 4", "2 * 3 * 4"},
+		{"an `unclosed span", "an `unclosed span"},
+		{"**größe**", mdBold + "größe" + mdBoldEnd},
+	}
+	for _, tt := range tests {
+		if got := renderInline(tt.text); got != tt.want {
+			t.Errorf("renderInline(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
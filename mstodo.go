@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MicrosoftConfig configures "sync mstodo" against Microsoft To Do
+type MicrosoftConfig struct {
+	// ClientID is the application (client) ID of an Azure app registration
+	// that allows public client flows
+	ClientID string `json:"client_id,omitempty"`
+	// Tenant defaults to "common"
+	Tenant string `json:"tenant,omitempty"`
+	// List is the display name of the To Do list to sync; the default
+	// list is used when empty
+	List string `json:"list,omitempty"`
+}
+
+// msDateTime is Graph's date and time with zone
+type msDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+// msTask is a task as returned by Microsoft Graph
+type msTask struct {
+	ID          string      `json:"id,omitempty"`
+	Title       string      `json:"title"`
+	Status      string      `json:"status,omitempty"`
+	DueDateTime *msDateTime `json:"dueDateTime,omitempty"`
+}
+
+const msGraphURL = "https://graph.microsoft.com/v1.0"
+const msScopes = "Tasks.ReadWrite offline_access"
+
+// msTaskURL returns the To Do web app link for a task
+func msTaskURL(id string) string {
+	return "https://to-do.office.com/tasks/id/" + url.PathEscape(id) + "/details"
+}
+
+// msClient talks to Microsoft Graph on behalf of the signed-in user
+type msClient struct {
+	cfg   MicrosoftConfig
+	token oauthToken
+}
+
+// postForm sends an OAuth form request and decodes the JSON reply whatever
+// the status, since OAuth errors are reported in the body
+func postForm(endpoint string, form url.Values, out interface{}) error {
+	resp, err := httpClient.PostForm(endpoint, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// tokenReply is the token endpoint's response
+type tokenReply struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// toToken converts a successful reply into a stored token
+func (r tokenReply) toToken() oauthToken {
+	return oauthToken{
+		AccessToken:  r.AccessToken,
+		RefreshToken: r.RefreshToken,
+		Expiry:       time.Now().Add(time.Duration(r.ExpiresIn) * time.Second),
+	}
+}
+
+// newMSClient loads the saved token, refreshing it or signing in with the
+// device-code flow as needed
+func newMSClient(cfg MicrosoftConfig) (*msClient, error) {
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("set microsoft.client_id in config")
+	}
+	if cfg.Tenant == "" {
+		cfg.Tenant = "common"
+	}
+	c := &msClient{cfg: cfg}
+
+	c.loadToken()
+	switch {
+	case c.token.AccessToken != "" && time.Now().Before(c.token.Expiry.Add(-time.Minute)):
+		return c, nil
+	case c.token.RefreshToken != "":
+		if err := c.refresh(); err == nil {
+			return c, c.saveToken()
+		}
+	}
+	if err := c.deviceLogin(); err != nil {
+		return nil, err
+	}
+	return c, c.saveToken()
+}
+
+// tokenURL returns the tenant's OAuth endpoint of the given kind
+func (c *msClient) tokenURL(kind string) string {
+	return "https://login.microsoftonline.com/" + url.PathEscape(c.cfg.Tenant) + "/oauth2/v2.0/" + kind
+}
+
+// refresh swaps the refresh token for a new access token
+func (c *msClient) refresh() error {
+	var reply tokenReply
+	err := postForm(c.tokenURL("token"), url.Values{
+		"client_id":     {c.cfg.ClientID},
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {c.token.RefreshToken},
+		"scope":         {msScopes},
+	}, &reply)
+	if err != nil {
+		return err
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s: %s", reply.Error, reply.Description)
+	}
+	c.token = reply.toToken()
+	return nil
+}
+
+// deviceLogin asks the user to sign in in a browser with a one-time code
+// and waits until they have done so
+func (c *msClient) deviceLogin() error {
+	var device struct {
+		DeviceCode string `json:"device_code"`
+		Message    string `json:"message"`
+		Interval   int    `json:"interval"`
+		ExpiresIn  int    `json:"expires_in"`
+		Error      string `json:"error"`
+	}
+	err := postForm(c.tokenURL("devicecode"), url.Values{
+		"client_id": {c.cfg.ClientID},
+		"scope":     {msScopes},
+	}, &device)
+	if err != nil {
+		return err
+	}
+	if device.Error != "" {
+		return fmt.Errorf("device login: %s", device.Error)
+	}
+	fmt.Println(device.Message)
+
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+		var reply tokenReply
+		err := postForm(c.tokenURL("token"), url.Values{
+			"client_id":   {c.cfg.ClientID},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {device.DeviceCode},
+		}, &reply)
+		if err != nil {
+			return err
+		}
+		switch reply.Error {
+		case "":
+			c.token = reply.toToken()
+			return nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return fmt.Errorf("device login: %s: %s", reply.Error, reply.Description)
+		}
+	}
+	return fmt.Errorf("device login timed out")
+}
+
+// loadToken reads the saved token from the system keychain, or from the
+// mstodo-token.json file older versions wrote
+func (c *msClient) loadToken() {
+	c.token = loadOAuthToken("mstodo "+c.cfg.ClientID, "mstodo-token.json")
+}
+
+// saveToken stores the token in the system keychain
+func (c *msClient) saveToken() error {
+	return saveOAuthToken("mstodo "+c.cfg.ClientID, "mstodo-token.json", c.token)
+}
+
+// do sends a Graph API request to path, or to a full URL for paging links
+func (c *msClient) do(method, path string, body, out interface{}) error {
+	if !strings.HasPrefix(path, "https://") {
+		path = msGraphURL + path
+	}
+	req, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token.AccessToken)
+	return doJSON(req, body, out)
+}
+
+// listID finds the configured To Do list
+func (c *msClient) listID() (string, error) {
+	var lists struct {
+		Value []struct {
+			ID                string `json:"id"`
+			DisplayName       string `json:"displayName"`
+			WellknownListName string `json:"wellknownListName"`
+		} `json:"value"`
+	}
+	if err := c.do("GET", "/me/todo/lists", nil, &lists); err != nil {
+		return "", err
+	}
+	for _, list := range lists.Value {
+		if (c.cfg.List == "" && list.WellknownListName == "defaultList") ||
+			(c.cfg.List != "" && strings.EqualFold(list.DisplayName, c.cfg.List)) {
+			return list.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no To Do list named %q", c.cfg.List)
+}
+
+// tasks returns every task in a list
+func (c *msClient) tasks(listID string) ([]msTask, error) {
+	var all []msTask
+	next := "/me/todo/lists/" + url.PathEscape(listID) + "/tasks"
+	for next != "" {
+		var page struct {
+			Value    []msTask `json:"value"`
+			NextLink string   `json:"@odata.nextLink"`
+		}
+		if err := c.do("GET", next, nil, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Value...)
+		next = page.NextLink
+	}
+	return all, nil
+}
+
+// msDue converts a deadline into Graph's dueDateTime
+func msDue(task *msTask, deadline time.Time) {
+	if deadline.IsZero() {
+		return
+	}
+	task.DueDateTime = &msDateTime{deadline.Format("2006-01-02") + "T00:00:00", "UTC"}
+}
+
+// syncMicrosoft syncs both ways with a Microsoft To Do list: remote tasks
+// are imported, completions flow in both directions and local tasks not
+// linked to any service are created remotely
+func syncMicrosoft(tasks []Task, cfg MicrosoftConfig) ([]Task, error) {
+	client, err := newMSClient(cfg)
+	if err != nil {
+		return tasks, err
+	}
+	listID, err := client.listID()
+	if err != nil {
+		return tasks, err
+	}
+	remoteTasks, err := client.tasks(listID)
+	if err != nil {
+		return tasks, err
+	}
+
+	taskPath := "/me/todo/lists/" + url.PathEscape(listID) + "/tasks"
+	var items []remoteItem
+	var completedHere, completedThere int
+	for _, rt := range remoteTasks {
+		remote := Remote{Source: "mstodo", ID: rt.ID, URL: msTaskURL(rt.ID)}
+		local := findRemote(tasks, remote)
+		switch {
+		case rt.Status == "completed":
+			if local != nil && !local.Done {
+				var done bool
+				if tasks, done = completeRemote(tasks, local.ID); done {
+					completedHere++
+				}
+			}
+		case local != nil && local.Done:
+			if err := client.do("PATCH", taskPath+"/"+url.PathEscape(rt.ID), msTask{Title: rt.Title, Status: "completed"}, nil); err != nil {
+				return tasks, err
+			}
+			completedThere++
+		default:
+			item := remoteItem{Remote: remote, Title: rt.Title}
+			if rt.DueDateTime != nil && len(rt.DueDateTime.DateTime) >= 10 {
+				item.Deadline, _ = time.Parse("2006-01-02", rt.DueDateTime.DateTime[:10])
+			}
+			items = append(items, item)
+		}
+	}
+
+	pushed := 0
+	for i := range tasks {
+		if tasks[i].Done || len(tasks[i].Remotes) > 0 {
+			continue
+		}
+		created := msTask{Title: tasks[i].Title}
+		msDue(&created, tasks[i].Deadline)
+		if err := client.do("POST", taskPath, created, &created); err != nil {
+			return tasks, err
+		}
+		tasks[i].Remotes = append(tasks[i].Remotes, Remote{Source: "mstodo", ID: created.ID, URL: msTaskURL(created.ID)})
+		pushed++
+	}
+
+	var added, updated int
+	tasks, added, updated = applyRemoteItems(tasks, items)
+	fmt.Printf("%sMicrosoft To Do: %d added, %d updated, %d pushed, %d completed locally, %d completed remotely%s\n",
+		green, added, updated, pushed, completedHere, completedThere, reset)
+	return tasks, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSyncMicrosoftReplay(t *testing.T) {
+	withConfig(t, Config{Microsoft: MicrosoftConfig{ClientID: "test-client"}})
+	// A token older versions left in a file, still valid
+	token := `{"access_token":"test-token","expiry":"2100-01-01T00:00:00Z"}`
+	if err := os.WriteFile(dataPath("mstodo-token.json"), []byte(token), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tasks := []Task{
+		{ID: 1, Title: "Pay rent", Remotes: []Remote{{Source: "mstodo", ID: "T2"}}},
+		{ID: 2, Title: "Call mom"},
+		{ID: 3, Title: "Book dentist", Done: true, Remotes: []Remote{{Source: "mstodo", ID: "T3"}}},
+	}
+	tasks = replay(t, tasks, "mstodo", "--replay-http", "testdata/mstodo.jsonl")
+
+	if len(tasks) != 4 {
+		t.Fatalf("got %d tasks, want 4: %+v", len(tasks), tasks)
+	}
+	if !findTask(tasks, 1).Done {
+		t.Error("task completed in To Do is still open here")
+	}
+	if mom := findTask(tasks, 2); len(mom.Remotes) != 1 || mom.Remotes[0].ID != "T9" {
+		t.Errorf("local task not pushed: %+v", mom.Remotes)
+	}
+	milk := findRemote(tasks, Remote{Source: "mstodo", ID: "T1"})
+	if milk == nil || milk.Title != "Buy milk" || milk.Deadline.Format("2006-01-02") != "2026-03-12" {
+		t.Errorf("remote task on the first page not imported: %+v", milk)
+	}
+}
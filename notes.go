@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// appendNote adds a paragraph to a task's notes
+func appendNote(notes, text string) string {
+	if notes == "" {
+		return text
+	}
+	return notes + "\n" + text
+}
+
+// editNotes opens notes in $VISUAL or $EDITOR (vi when neither is set) and
+// returns the saved text
+func editNotes(notes string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	file, err := os.CreateTemp("", "todo-note-*.md")
+	if err != nil {
+		return notes, err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(notes); err != nil {
+		file.Close()
+		return notes, err
+	}
+	if err := file.Close(); err != nil {
+		return notes, err
+	}
+
+	// The editor setting may carry arguments, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], file.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return notes, err
+	}
+	edited, err := os.ReadFile(file.Name())
+	if err != nil {
+		return notes, err
+	}
+	return strings.TrimRight(string(edited), "\n"), nil
+}
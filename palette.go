@@ -0,0 +1,27 @@
+package main
+
+import "time"
+
+// useAccessiblePalette swaps red and green for a blue/orange palette that
+// people with red-green color blindness can tell apart
+func useAccessiblePalette() {
+	green = "\033[34m"
+	red = "\033[38;5;208m"
+	yellow = "\033[1;33m"
+}
+
+// deadlineMarker returns a text label for what the deadline color says,
+// so status doesn't depend on color alone. It is empty unless the
+// accessible option is on.
+func deadlineMarker(task Task, now time.Time) string {
+	if !config.Accessible {
+		return ""
+	}
+	if isOverdue(task, now) {
+		return " [OVERDUE]"
+	}
+	if !task.Done && isDueToday(task, now) {
+		return " [TODAY]"
+	}
+	return ""
+}
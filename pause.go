@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// paused reports whether a recurring task is on hold on now's date. A
+// pause with an end date lifts by itself on that day.
+func (t Task) paused(now time.Time) bool {
+	return t.Paused && (t.PausedUntil.IsZero() || logicalDate(now).Before(dateOf(t.PausedUntil)))
+}
+
+// pauseTask puts a recurring task on hold, until the given date or until
+// it is resumed when until is zero. Paused tasks send no reminders, and
+// with an end date the deadline moves to the first occurrence from then
+// on, so the task comes back by itself.
+func pauseTask(tasks []Task, id int, until, now time.Time) ([]Task, error) {
+	task := findTask(tasks, id)
+	if task == nil {
+		return tasks, fmt.Errorf("task #%d not found", id)
+	}
+	if task.Repeat == "" {
+		return tasks, fmt.Errorf("task #%d does not repeat, only recurring tasks can be paused", id)
+	}
+	if task.Done {
+		return tasks, fmt.Errorf("task #%d is done", id)
+	}
+	if !until.IsZero() {
+		until = dateOf(until)
+		if !until.After(logicalDate(now)) {
+			return tasks, fmt.Errorf("--until must be after today")
+		}
+		if !task.Deadline.IsZero() && dateOf(task.Deadline).Before(until) {
+			next, err := nextOccurrence(task.Repeat, dateOf(task.Deadline), until)
+			if err != nil {
+				return tasks, err
+			}
+			task.Deadline = deadlineOn(next)
+		}
+	}
+	task.Paused = true
+	task.PausedUntil = until
+	return tasks, nil
+}
+
+// resumeTask takes a task off hold. Occurrences missed while it was
+// paused are skipped: a deadline in the past moves to the next occurrence
+// from today.
+func resumeTask(tasks []Task, id int, now time.Time) ([]Task, error) {
+	task := findTask(tasks, id)
+	if task == nil {
+		return tasks, fmt.Errorf("task #%d not found", id)
+	}
+	if !task.paused(now) {
+		return tasks, fmt.Errorf("task #%d is not paused", id)
+	}
+	today := logicalDate(now)
+	if !task.Deadline.IsZero() && dateOf(task.Deadline).Before(today) {
+		next, err := nextOccurrence(task.Repeat, dateOf(task.Deadline), today)
+		if err != nil && err != errRepeatEnded {
+			return tasks, err
+		}
+		if err == nil {
+			task.Deadline = deadlineOn(next)
+		}
+	}
+	task.Paused = false
+	task.PausedUntil = time.Time{}
+	return tasks, nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import "sort"
+
+// pinMarker is shown before the title of pinned tasks
+const pinMarker = "📌 "
+
+// pinnedFirst returns the tasks with pinned ones moved to the top, keeping
+// the existing order otherwise
+func pinnedFirst(tasks []Task) []Task {
+	sorted := append([]Task(nil), tasks...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Pinned && !sorted[j].Pinned })
+	return sorted
+}
+
+// setPinned pins or unpins a task by ID
+func setPinned(tasks []Task, id int, pinned bool) ([]Task, bool) {
+	task := findTask(tasks, id)
+	if task == nil {
+		return tasks, false
+	}
+	task.Pinned = pinned
+	return tasks, true
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Plan is the ordered agenda chosen for one day
+type Plan struct {
+	Date time.Time `json:"date"`
+	IDs  []int     `json:"ids"`
+}
+
+// loadPlan reads the saved plan from plan.json file
+func loadPlan() (Plan, error) {
+	var plan Plan
+	file, err := os.ReadFile(dataPath("plan.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return plan, nil
+		}
+		return plan, err
+	}
+	err = json.Unmarshal(file, &plan)
+	return plan, err
+}
+
+// savePlan writes the plan to plan.json file
+func savePlan(plan Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dataPath("plan.json"), data, 0644)
+}
+
+// isFor reports whether the plan was made for the day containing now
+func (p Plan) isFor(now time.Time) bool {
+	return !p.Date.IsZero() && dateOf(p.Date).Equal(logicalDate(now))
+}
+
+// planCandidates returns unfinished tasks worth planning for today:
+// overdue ones first, then those due today, then other high priority
+// tasks. Within each group higher priorities come first, then earlier
+// deadlines.
+func planCandidates(tasks []Task, now time.Time) []Task {
+	group := func(task Task) int {
+		switch {
+		case isOverdue(task, now):
+			return 0
+		case isDueToday(task, now):
+			return 1
+		}
+		return 2
+	}
+	var candidates []Task
+	for _, task := range tasks {
+		if !task.Done && (group(task) < 2 || task.Priority == priorityHigh) {
+			candidates = append(candidates, task)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if group(a) != group(b) {
+			return group(a) < group(b)
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if a.Deadline.IsZero() != b.Deadline.IsZero() {
+			return b.Deadline.IsZero()
+		}
+		return a.Deadline.Before(b.Deadline)
+	})
+	return candidates
+}
+
+// planDay walks through the candidates asking whether to accept, skip or
+// defer each one. Deferred tasks move to tomorrow's deadline.
+func planDay(tasks []Task, in io.Reader, now time.Time) ([]Task, Plan) {
+	plan := Plan{Date: logicalDate(now)}
+	tomorrow := logicalDate(now).AddDate(0, 0, 1)
+	reader := bufio.NewReader(in)
+
+	candidates := planCandidates(tasks, now)
+	for i, candidate := range candidates {
+		due := ""
+		if !candidate.Deadline.IsZero() {
+			due = " (Deadline: " + candidate.Deadline.Format("2006-01-02") + ")"
+		}
+		fmt.Printf("(%d/%d) #%d: %s%s%s\n", i+1, len(candidates),
+			candidate.ID, priorityLabel(candidate.Priority), candidate.Title, due)
+		switch askChoice(reader, "  [a]ccept, [s]kip, [d]efer to tomorrow, [q]uit? ", "asdq") {
+		case "a":
+			plan.IDs = append(plan.IDs, candidate.ID)
+		case "d":
+			findTask(tasks, candidate.ID).Deadline = deadlineOn(tomorrow)
+		case "q":
+			return tasks, plan
+		}
+	}
+	return tasks, plan
+}
+
+// askChoice prompts until the answer is one of the letters in choices. An
+// empty answer picks the first choice and end of input picks the last.
+func askChoice(reader *bufio.Reader, prompt string, choices string) string {
+	for {
+		fmt.Print(prompt)
+		line, err := reader.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer == "" {
+			if err != nil {
+				fmt.Println()
+				return choices[len(choices)-1:]
+			}
+			return choices[:1]
+		}
+		if len(answer) == 1 && strings.Contains(choices, answer) {
+			return answer
+		}
+		if err != nil {
+			fmt.Println()
+			return choices[len(choices)-1:]
+		}
+	}
+}
+
+// confirm asks a yes/no question, treating anything but yes as no
+func confirm(reader *bufio.Reader, prompt string) bool {
+	fmt.Print(prompt + " [y/N] ")
+	line, _ := reader.ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
+
+// printPlan shows the plan in order with each task's status
+func printPlan(tasks []Task, plan Plan) {
+	for i, id := range plan.IDs {
+		task := findTask(tasks, id)
+		if task == nil {
+			continue
+		}
+		status := red + "Not Done" + reset
+		if task.Done {
+			status = green + "Done" + reset
+		}
+		fmt.Printf("%d. #%d: %s [%s]\n", i+1, task.ID, task.Title, status)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// pluginPrefix starts the name of provider executables on the PATH, e.g.
+// todo-provider-todoist
+const pluginPrefix = "todo-provider-"
+
+// pluginTask is a task as sent to a provider plugin. RemoteID is set when
+// the task is linked to an item of that provider.
+type pluginTask struct {
+	ID       int      `json:"id"`
+	RemoteID string   `json:"remote_id,omitempty"`
+	Title    string   `json:"title"`
+	Done     bool     `json:"done"`
+	Deadline string   `json:"deadline,omitempty"`
+	Project  string   `json:"project,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// pluginRequest is written to a plugin's stdin as one JSON document.
+// Action is "sync", "import" or "export".
+type pluginRequest struct {
+	Version int             `json:"version"`
+	Action  string          `json:"action"`
+	Args    []string        `json:"args,omitempty"`
+	Config  json.RawMessage `json:"config,omitempty"`
+	Tasks   []pluginTask    `json:"tasks"`
+	Input   string          `json:"input,omitempty"`
+}
+
+// pluginItem is an item reported by a plugin
+type pluginItem struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Deadline string `json:"deadline,omitempty"`
+	Done     bool   `json:"done,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// pluginResponse is read from a plugin's stdout. Sync and import answer
+// with items, export with output.
+type pluginResponse struct {
+	Items  []pluginItem `json:"items"`
+	Output string       `json:"output"`
+	Error  string       `json:"error"`
+}
+
+// findPlugin returns the path of the plugin executable for name, or ""
+func findPlugin(name string) string {
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// pluginTasks converts tasks for a plugin, linking them to its items
+func pluginTasks(tasks []Task, name string) []pluginTask {
+	converted := make([]pluginTask, 0, len(tasks))
+	for _, task := range tasks {
+		pt := pluginTask{ID: task.ID, Title: task.Title, Done: task.Done, Project: task.Project, Tags: task.Tags}
+		if !task.Deadline.IsZero() {
+			pt.Deadline = task.Deadline.Format("2006-01-02")
+		}
+		for _, remote := range task.Remotes {
+			if remote.Source == name {
+				pt.RemoteID = remote.ID
+			}
+		}
+		converted = append(converted, pt)
+	}
+	return converted
+}
+
+// runPlugin sends a request to the named plugin and returns its response
+func runPlugin(name string, req pluginRequest) (pluginResponse, error) {
+	var resp pluginResponse
+	path := findPlugin(name)
+	if path == "" {
+		return resp, fmt.Errorf("no %s%s found on PATH", pluginPrefix, name)
+	}
+	req.Version = 1
+	req.Config = config.Plugins[name]
+	input, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	var out bytes.Buffer
+	cmd := exec.Command(path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(input), &out, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return resp, fmt.Errorf("%s: %v", pluginPrefix+name, err)
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		return resp, fmt.Errorf("%s: invalid response: %v", pluginPrefix+name, err)
+	}
+	if resp.Error != "" {
+		return resp, fmt.Errorf("%s: %s", pluginPrefix+name, resp.Error)
+	}
+	return resp, nil
+}
+
+// remoteItems turns plugin items into remote items of that source
+func (r pluginResponse) remoteItems(name string) ([]remoteItem, error) {
+	var items []remoteItem
+	for _, item := range r.Items {
+		if item.ID == "" || item.Title == "" {
+			return nil, fmt.Errorf("%s: item without id or title", pluginPrefix+name)
+		}
+		ri := remoteItem{Remote: Remote{Source: name, ID: item.ID, URL: item.URL}, Title: item.Title}
+		if item.Deadline != "" {
+			deadline, err := time.Parse("2006-01-02", item.Deadline)
+			if err != nil {
+				return nil, fmt.Errorf("%s: item %s: invalid deadline %q", pluginPrefix+name, item.ID, item.Deadline)
+			}
+			ri.Deadline = deadline
+		}
+		items = append(items, ri)
+	}
+	return items, nil
+}
+
+// syncPlugin syncs with a provider plugin, passing it any extra arguments.
+// The plugin sees every task so it can push local completions, and reports
+// its open and finished items.
+func syncPlugin(tasks []Task, name string, args []string) ([]Task, error) {
+	resp, err := runPlugin(name, pluginRequest{Action: "sync", Args: args, Tasks: pluginTasks(tasks, name)})
+	if err != nil {
+		return tasks, err
+	}
+	items, err := resp.remoteItems(name)
+	if err != nil {
+		return tasks, err
+	}
+
+	var open []remoteItem
+	completed := 0
+	for i, item := range items {
+		if !resp.Items[i].Done {
+			open = append(open, item)
+			continue
+		}
+		if task := findRemote(tasks, item.Remote); task != nil && !task.Done {
+			var done bool
+			if tasks, done = completeRemote(tasks, task.ID); done {
+				completed++
+			}
+		}
+	}
+	var added, updated int
+	tasks, added, updated = applyRemoteItems(tasks, open)
+	fmt.Printf("%s%s: %d added, %d updated, %d completed%s\n", green, name, added, updated, completed, reset)
+	return tasks, nil
+}
+
+// importPlugin asks a plugin to read a file in its format
+func importPlugin(tasks []Task, name string, data []byte) ([]Task, int, int, error) {
+	resp, err := runPlugin(name, pluginRequest{Action: "import", Tasks: pluginTasks(tasks, name), Input: string(data)})
+	if err != nil {
+		return tasks, 0, 0, err
+	}
+	items, err := resp.remoteItems(name)
+	if err != nil {
+		return tasks, 0, 0, err
+	}
+	tasks, added, updated := applyRemoteItems(tasks, items)
+	return tasks, added, updated, nil
+}
+
+// exportPlugin asks a plugin to render tasks in its format
+func exportPlugin(tasks []Task, name string) ([]byte, error) {
+	resp, err := runPlugin(name, pluginRequest{Action: "export", Tasks: pluginTasks(tasks, name)})
+	return []byte(resp.Output), err
+}
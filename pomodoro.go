@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// pomodoroTick is how often the pomodoro countdown redraws
+const pomodoroTick = time.Second
+
+// runTimer counts a period down on one terminal line
+func runTimer(label string, length time.Duration) {
+	end := time.Now().Add(length)
+	for left := length; left > 0; left = time.Until(end) {
+		seconds := int(left.Round(time.Second) / time.Second)
+		fmt.Printf("\r%s %02d:%02d ", label, seconds/60, seconds%60)
+		if left < pomodoroTick {
+			time.Sleep(left)
+			break
+		}
+		time.Sleep(pomodoroTick)
+	}
+	fmt.Printf("\r%s 00:00\n", label)
+}
+
+// logPomodoro counts a finished pomodoro on the task and records the work
+// as a tracked session unless the task is already being tracked. It saves straight away so an interrupted run keeps
+// the pomodoros already done.
+func logPomodoro(id int, start, end time.Time) (int, error) {
+	tasks, err := loadTasks()
+	if err != nil {
+		return 0, err
+	}
+	original := copyTasks(tasks)
+	task := findTask(tasks, id)
+	if task == nil {
+		return 0, fmt.Errorf("task #%d not found", id)
+	}
+	task.Pomodoros++
+	if !task.tracking() {
+		// A running start/stop session already counts this time
+		task.Sessions = append(task.Sessions, Session{Start: start, End: end})
+	}
+	count := task.Pomodoros
+	if err := saveTasks(tasks); err != nil {
+		return 0, err
+	}
+	return count, recordOperation("pomodoro", original, tasks)
+}
+
+// cycleEnded signals the end of a work period or break with a terminal
+// bell and, when asked, a desktop notification
+func cycleEnded(title, body string, desktop bool) {
+	fmt.Print("\a")
+	if desktop {
+		alert(title, body)
+	}
+}
+
+// pomodoro runs cycles of work and break on a task
+func pomodoro(task Task, work, rest time.Duration, cycles int, desktop bool) error {
+	for cycle := 1; cycle <= cycles; cycle++ {
+		start := time.Now()
+		runTimer(fmt.Sprintf("Pomodoro %d/%d on #%d %s", cycle, cycles, task.ID, task.Title), work)
+		count, err := logPomodoro(task.ID, start, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%sPomodoro done, %d on task #%d so far%s\n", green, count, task.ID, reset)
+		if cycle == cycles {
+			cycleEnded(task.Title, "Pomodoro done", desktop)
+			break
+		}
+		cycleEnded(task.Title, "Pomodoro done, take a break", desktop)
+		runTimer("Break", rest)
+		cycleEnded(task.Title, "Break over, back to work", desktop)
+	}
+	return nil
+}
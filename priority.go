@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Priority levels; tasks without a priority are priorityNone
+const (
+	priorityNone = iota
+	priorityLow
+	priorityMedium
+	priorityHigh
+)
+
+// priorityNames are the level names indexed by priority
+var priorityNames = []string{"none", "low", "medium", "high"}
+
+// parsePriority accepts a level name, its first letter or its number 0-3
+func parsePriority(level string) (int, error) {
+	level = strings.ToLower(level)
+	if n, err := strconv.Atoi(level); err == nil && n >= priorityNone && n <= priorityHigh {
+		return n, nil
+	}
+	for p, name := range priorityNames {
+		if level == name || (level != "" && level == name[:1]) {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid priority %q, use low, medium or high", level)
+}
+
+// priorityLabel returns a colored marker such as "[high] " to put before a
+// task's title, or "" when it has no priority
+func priorityLabel(priority int) string {
+	switch priority {
+	case priorityHigh:
+		return red + "[high]" + reset + " "
+	case priorityMedium:
+		return yellow + "[medium]" + reset + " "
+	case priorityLow:
+		return "[low] "
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// shareableConfig returns the settings a team can share. Fields are
+// copied one by one so that anything not listed here, such as a field
+// added later, stays out of the bundle. Left out are secrets (the Google
+// client secret and plugin settings, which may hold tokens) and settings
+// that only make sense for one person: user names, the digest email
+// address, the notes folder and the CalDAV and Google calendars.
+func shareableConfig(cfg Config) Config {
+	shared := Config{
+		DayEnd:         cfg.DayEnd,
+		DeadlineTime:   cfg.DeadlineTime,
+		DailyLimit:     cfg.DailyLimit,
+		WeekStart:      cfg.WeekStart,
+		DefaultProject: cfg.DefaultProject,
+		Accessible:     cfg.Accessible,
+		QuietHours:     cfg.QuietHours,
+		Digest:         DigestConfig{Times: cfg.Digest.Times, Priorities: cfg.Digest.Priorities},
+		WIPLimit:       cfg.WIPLimit,
+		RemindBefore:   cfg.RemindBefore,
+		Escalation:     cfg.Escalation,
+		Columns:        cfg.Columns,
+		GitLab:         GitLabConfig{URL: cfg.GitLab.URL, TokenEnv: cfg.GitLab.TokenEnv},
+		Microsoft:      MicrosoftConfig{ClientID: cfg.Microsoft.ClientID, Tenant: cfg.Microsoft.Tenant, List: cfg.Microsoft.List},
+		Vault:          VaultConfig{Tag: cfg.Vault.Tag},
+		CalDAV:         CalDAVConfig{PasswordEnv: cfg.CalDAV.PasswordEnv},
+		Google:         GoogleConfig{ClientID: cfg.Google.ClientID},
+	}
+	if len(cfg.Jira) > 0 {
+		shared.Jira = map[string]JiraConfig{}
+	}
+	for name, jira := range cfg.Jira {
+		shared.Jira[name] = JiraConfig{
+			URL:            jira.URL,
+			TokenEnv:       jira.TokenEnv,
+			JQL:            jira.JQL,
+			DoneTransition: jira.DoneTransition,
+			TitleField:     jira.TitleField,
+			DeadlineField:  jira.DeadlineField,
+		}
+	}
+	return shared
+}
+
+// exportConfig writes a shareable copy of the config to path, or to
+// stdout when path is empty
+func exportConfig(cfg Config, path string) error {
+	data, err := json.MarshalIndent(shareableConfig(cfg), "", "  ")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// importConfig replaces the config with one exported elsewhere, keeping
+// this machine's personal settings where the import leaves them blank
+func importConfig(cfg Config, path string, force bool, in *bufio.Reader) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	imported, err := parseConfig(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for name, jira := range imported.Jira {
+		if jira.User == "" {
+			jira.User = cfg.Jira[name].User
+			imported.Jira[name] = jira
+		}
+	}
+	keep := func(field *string, current string) {
+		if *field == "" {
+			*field = current
+		}
+	}
+	keep(&imported.Digest.Email, cfg.Digest.Email)
+	keep(&imported.Vault.Path, cfg.Vault.Path)
+	keep(&imported.CalDAV.URL, cfg.CalDAV.URL)
+	keep(&imported.CalDAV.User, cfg.CalDAV.User)
+	keep(&imported.Google.ClientSecret, cfg.Google.ClientSecret)
+	keep(&imported.Google.Calendar, cfg.Google.Calendar)
+	if imported.Plugins == nil {
+		imported.Plugins = cfg.Plugins
+	}
+
+	if _, err := os.Stat(dataPath("config.json")); err == nil && !force && !confirm(in, "Replace the current config.json?") {
+		return fmt.Errorf("import cancelled")
+	}
+	out, err := json.MarshalIndent(imported, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dataPath("config.json"), append(out, '\n'), 0644)
+}
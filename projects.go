@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Project groups related tasks and can be closed once finished. Tasks
+// added to it start with its default priority and tags.
+type Project struct {
+	Name     string    `json:"name"`
+	Created  time.Time `json:"created"`
+	Closed   bool      `json:"closed,omitempty"`
+	ClosedAt time.Time `json:"closed_at,omitempty"`
+	Priority int       `json:"priority,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+}
+
+// loadProjects reads projects from projects.json file
+func loadProjects() ([]Project, error) {
+	file, err := os.ReadFile(dataPath("projects.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Project{}, nil
+		}
+		return nil, err
+	}
+
+	var projects []Project
+	if err := json.Unmarshal(file, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// saveProjects writes projects to projects.json file
+func saveProjects(projects []Project) error {
+	data, err := json.MarshalIndent(projects, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dataPath("projects.json"), data, 0644)
+}
+
+// findProject returns the project with the given name, ignoring case
+func findProject(projects []Project, name string) *Project {
+	for i := range projects {
+		if strings.EqualFold(projects[i].Name, name) {
+			return &projects[i]
+		}
+	}
+	return nil
+}
+
+// openProject returns the named project, failing if it is missing or closed
+func openProject(name string) (*Project, error) {
+	projects, err := loadProjects()
+	if err != nil {
+		return nil, err
+	}
+	project := findProject(projects, name)
+	if project == nil {
+		return nil, fmt.Errorf("project %q not found, create it with \"project add\"", name)
+	}
+	if project.Closed {
+		return nil, fmt.Errorf("project %q is closed", project.Name)
+	}
+	return project, nil
+}
+
+// join puts a new task in the project with the project's default priority
+// and tags, before any given for the task itself are applied
+func (p *Project) join(task *Task) {
+	task.Project = p.Name
+	if task.Priority == priorityNone {
+		task.Priority = p.Priority
+	}
+	task.Tags = addTags(task.Tags, p.Tags)
+}
+
+// projectTasks returns the tasks belonging to a project
+func projectTasks(tasks []Task, name string) []Task {
+	var matched []Task
+	for _, task := range tasks {
+		if strings.EqualFold(task.Project, name) {
+			matched = append(matched, task)
+		}
+	}
+	return matched
+}
+
+// closeProject asks what to do with each unfinished task of a project:
+// mark it done, delete it, or keep it without a project
+func closeProject(tasks []Task, project *Project, in io.Reader) ([]Task, error) {
+	reader := bufio.NewReader(in)
+	// Subtasks come before their parents, which cannot be finished while
+	// they are open
+	members := projectTasks(tasks, project.Name)
+	depth := func(task Task) int {
+		n := 0
+		for parent := findTask(tasks, task.ParentID); parent != nil && n < len(tasks); parent = findTask(tasks, parent.ParentID) {
+			n++
+		}
+		return n
+	}
+	sort.SliceStable(members, func(i, j int) bool { return depth(members[i]) > depth(members[j]) })
+	for _, task := range members {
+		// Finishing a subtask may have finished its parent too
+		if current := findTask(tasks, task.ID); current == nil || current.Done {
+			continue
+		}
+		fmt.Printf("#%d: %s is not done\n", task.ID, task.Title)
+		for {
+			switch askChoice(reader, "  [d]one, [r]emove, [k]eep without project, [a]bort? ", "drka") {
+			case "d":
+				var err error
+				if tasks, _, err = completeTask(tasks, task.ID, time.Now()); err != nil {
+					if current := findTask(tasks, task.ID); current == nil || !current.Done {
+						fmt.Printf("  %s%v%s\n", yellow, err, reset)
+						continue
+					}
+					return tasks, err
+				}
+			case "r":
+				tasks, _ = deleteTask(tasks, task.ID)
+			case "k":
+				findTask(tasks, task.ID).Project = ""
+			case "a":
+				return tasks, fmt.Errorf("closing %q aborted", project.Name)
+			}
+			break
+		}
+	}
+	project.Closed = true
+	project.ClosedAt = time.Now()
+	return tasks, nil
+}
+
+// printProjects lists projects with their open and total task counts
+func printProjects(projects []Project, tasks []Task) {
+	if len(projects) == 0 {
+		fmt.Println(yellow + "No projects found" + reset)
+		return
+	}
+	for _, project := range projects {
+		members := projectTasks(tasks, project.Name)
+		open := 0
+		for _, task := range members {
+			if !task.Done {
+				open++
+			}
+		}
+		state := green + "open" + reset
+		if project.Closed {
+			state = "closed " + project.ClosedAt.Format("2006-01-02")
+		}
+		defaults := tagLabels(project.Tags)
+		if project.Priority != priorityNone {
+			defaults = " [" + priorityNames[project.Priority] + "]" + defaults
+		}
+		fmt.Printf("%s [%s] %d open / %d tasks%s\n", project.Name, state, open, len(members), defaults)
+	}
+}
+
+// projectCommand runs "project add|set|close|list"
+func projectCommand(tasks []Task, args []string) ([]Task, error) {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	projects, err := loadProjects()
+	if err != nil {
+		return tasks, err
+	}
+
+	switch args[0] {
+	case "list":
+		printProjects(projects, tasks)
+		return tasks, nil
+	case "add":
+		if len(args) < 2 || strings.TrimSpace(args[1]) == "" {
+			return tasks, fmt.Errorf("project name is required")
+		}
+		if findProject(projects, args[1]) != nil {
+			return tasks, fmt.Errorf("project %q already exists", args[1])
+		}
+		projects = append(projects, Project{Name: args[1], Created: time.Now()})
+		if err := saveProjects(projects); err != nil {
+			return tasks, err
+		}
+		fmt.Printf("%sAdded project %s%s\n", green, args[1], reset)
+		return tasks, nil
+	case "set":
+		rest, flags, err := parseFlags(args[1:], "priority", "tag")
+		if err != nil {
+			return tasks, err
+		}
+		if len(rest) < 1 {
+			return tasks, fmt.Errorf("project name is required")
+		}
+		project := findProject(projects, rest[0])
+		if project == nil {
+			return tasks, fmt.Errorf("project %q not found", rest[0])
+		}
+		if !flags.has("priority") && !flags.has("tag") && !flags.has("no-tags") {
+			return tasks, fmt.Errorf("nothing to set, use --priority, --tag or --no-tags")
+		}
+		if flags.has("priority") {
+			if project.Priority, err = parsePriority(flags.get("priority")); err != nil {
+				return tasks, err
+			}
+		}
+		if flags.has("tag") || flags.has("no-tags") {
+			// The given tags replace the old defaults
+			project.Tags = addTags(nil, flags["tag"])
+		}
+		if err := saveProjects(projects); err != nil {
+			return tasks, err
+		}
+		fmt.Printf("%sNew tasks in %s get priority %s and tags:%s%s\n", green, project.Name, priorityNames[project.Priority], reset, tagLabels(project.Tags))
+		return tasks, nil
+	case "close":
+		if len(args) < 2 {
+			return tasks, fmt.Errorf("project name is required")
+		}
+		project := findProject(projects, args[1])
+		if project == nil {
+			return tasks, fmt.Errorf("project %q not found", args[1])
+		}
+		if project.Closed {
+			return tasks, fmt.Errorf("project %q is already closed", project.Name)
+		}
+		if tasks, err = closeProject(tasks, project, os.Stdin); err != nil {
+			return tasks, err
+		}
+		if err := saveProjects(projects); err != nil {
+			return tasks, err
+		}
+		fmt.Printf("%sClosed project %s%s\n", yellow, project.Name, reset)
+		return tasks, nil
+	default:
+		return tasks, fmt.Errorf("unknown project command %q", args[0])
+	}
+}
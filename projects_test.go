@@ -0,0 +1,23 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProjectJoin(t *testing.T) {
+	project := &Project{Name: "Acme", Priority: priorityHigh, Tags: []string{"client", "billable"}}
+
+	task := Task{ID: 1, Title: "Kickoff"}
+	project.join(&task)
+	if task.Project != "Acme" || task.Priority != priorityHigh || !reflect.DeepEqual(task.Tags, []string{"client", "billable"}) {
+		t.Errorf("join = %q %d %v, want Acme, high and the default tags", task.Project, task.Priority, task.Tags)
+	}
+
+	// A priority or tags the task already has are kept
+	task = Task{ID: 2, Title: "Invoice", Priority: priorityLow, Tags: []string{"Client", "money"}}
+	project.join(&task)
+	if task.Priority != priorityLow || !reflect.DeepEqual(task.Tags, []string{"Client", "money", "billable"}) {
+		t.Errorf("join = %d %v, want low and [Client money billable]", task.Priority, task.Tags)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Where the next occurrence of a recurring task is counted from
+const (
+	repeatFromDue  = "due"
+	repeatFromDone = "done"
+)
+
+// cronSearchDays bounds the search for the next date matching a cron rule
+const cronSearchDays = 5 * 366
+
+// cronField is the set of values a cron field allows
+type cronField map[int]bool
+
+// parseCronField reads one cron field: "*", numbers, ranges "a-b", lists
+// "a,b" and steps "*/n" or "a-b/n"
+func parseCronField(field string, min, max int) (cronField, error) {
+	allowed := cronField{}
+	for _, part := range strings.Split(field, ",") {
+		spec, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if spec != "*" {
+			from, to, isRange := strings.Cut(spec, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return nil, fmt.Errorf("invalid range %q", part)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			allowed[v] = true
+		}
+	}
+	return allowed, nil
+}
+
+// parseCron reads a five-field cron expression. Deadlines are dates, so
+// only the day of month, month and day of week fields matter; minute and
+// hour are checked but ignored.
+func parseCron(expr string) (func(time.Time) bool, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q needs 5 fields", expr)
+	}
+	limits := [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	parsed := make([]cronField, 5)
+	for i, field := range fields {
+		var err error
+		if parsed[i], err = parseCronField(field, limits[i][0], limits[i][1]); err != nil {
+			return nil, fmt.Errorf("cron expression %q: %v", expr, err)
+		}
+	}
+	days, months, weekdays := parsed[2], parsed[3], parsed[4]
+	if weekdays[7] {
+		weekdays[0] = true
+	}
+	// As in cron, a restricted day of month and day of week match either
+	anyDay, anyWeekday := fields[2] == "*", fields[4] == "*"
+	return func(date time.Time) bool {
+		if !months[int(date.Month())] {
+			return false
+		}
+		dayOK, weekdayOK := days[date.Day()], weekdays[int(date.Weekday())]
+		switch {
+		case anyDay:
+			return weekdayOK
+		case anyWeekday:
+			return dayOK
+		}
+		return dayOK || weekdayOK
+	}, nil
+}
+
+// checkRepeat validates a --repeat rule: daily, weekly, monthly, yearly, an
+// interval such as 30d, a cron expression or an RRULE that matches some
+// date
+func checkRepeat(rule string) error {
+	today := logicalDate(time.Now())
+	_, err := nextOccurrence(rule, today, today)
+	return err
+}
+
+// addMonths moves a date by months, keeping to the last day of shorter
+// months instead of spilling into the next one
+func addMonths(date time.Time, months int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(months), 1, 0, 0, 0, 0, date.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	day := date.Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// nextOccurrence returns the first date after base that the rule allows and
+// that is not before today. Steps count from base, so a late weekly chore
+// lands on its regular weekday and a task due on the 31st stays at the end
+// of the month.
+func nextOccurrence(rule string, base, today time.Time) (time.Time, error) {
+	var days, months int
+	switch rule {
+	case "daily":
+		days = 1
+	case "weekly":
+		days = 7
+	case "monthly":
+		months = 1
+	case "yearly":
+		months = 12
+	default:
+		if isRRule(rule) {
+			r, err := parseRRule(rule)
+			if err != nil {
+				return base, err
+			}
+			return r.next(base, today)
+		}
+		// An interval such as "30d" or "2w"
+		if years, m, d, err := parseOffset(rule); err == nil && !strings.HasPrefix(rule, "+") {
+			if years < 0 || m < 0 || d < 0 || years+m+d == 0 {
+				return base, fmt.Errorf("repeat interval %q must be positive", rule)
+			}
+			days, months = d, 12*years+m
+			break
+		}
+		match, err := parseCron(rule)
+		if err != nil {
+			return base, err
+		}
+		from := base
+		if yesterday := today.AddDate(0, 0, -1); from.Before(yesterday) {
+			from = yesterday
+		}
+		for i := 1; i <= cronSearchDays; i++ {
+			if date := from.AddDate(0, 0, i); match(date) {
+				return date, nil
+			}
+		}
+		return base, fmt.Errorf("cron expression %q never matches", rule)
+	}
+	for n := 1; ; n++ {
+		next := addMonths(base.AddDate(0, 0, n*days), n*months)
+		if !next.Before(today) {
+			return next, nil
+		}
+	}
+}
+
+// checkRepeatFrom validates a --repeat-from anchor
+func checkRepeatFrom(from string) error {
+	if from != repeatFromDue && from != repeatFromDone {
+		return fmt.Errorf("invalid --repeat-from %q, use due or done", from)
+	}
+	return nil
+}
+
+// repeatTask adds the next occurrence of a finished recurring task and
+// returns its ID, or 0 when the task does not repeat. Occurrences follow
+// from the deadline, or from the day the task was done for tasks that
+// repeat from done. The next occurrence of a paused task stays paused,
+// and falls after the pause when it has an end date.
+func repeatTask(tasks []Task, id int, now time.Time) ([]Task, int, error) {
+	task := findTask(tasks, id)
+	if task == nil || task.Repeat == "" {
+		return tasks, 0, nil
+	}
+	today := logicalDate(now)
+	base := dateOf(task.Deadline)
+	if task.Deadline.IsZero() || task.RepeatFrom == repeatFromDone {
+		base = today
+	}
+	paused := task.paused(now)
+	if paused && !task.PausedUntil.IsZero() {
+		today = dateOf(task.PausedUntil)
+	}
+	next, err := nextOccurrence(task.Repeat, base, today)
+	if err == errRepeatEnded {
+		return tasks, 0, nil
+	}
+	if err != nil {
+		return tasks, 0, err
+	}
+
+	occurrence := *task
+	var newID int
+	tasks, newID = addTask(tasks, occurrence.Title, "")
+	added := findTask(tasks, newID)
+	added.Deadline = deadlineOn(next)
+	added.ParentID = occurrence.ParentID
+	added.Project = occurrence.Project
+	added.Priority = occurrence.Priority
+	added.Tags = append([]string(nil), occurrence.Tags...)
+	added.Pinned = occurrence.Pinned
+	added.Notes = occurrence.Notes
+	added.Repeat = occurrence.Repeat
+	added.RepeatFrom = occurrence.RepeatFrom
+	if paused {
+		added.Paused, added.PausedUntil = true, occurrence.PausedUntil
+	}
+	return tasks, newID, nil
+}
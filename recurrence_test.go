@@ -0,0 +1,146 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr  string
+		date  string
+		match bool
+	}{
+		{"0 9 * * 1-5", "2026-03-06", true},  // Friday
+		{"0 9 * * 1-5", "2026-03-07", false}, // Saturday
+		{"0 0 * * 0", "2026-03-08", true},
+		{"0 0 * * 7", "2026-03-08", true}, // 7 is Sunday too
+		{"0 0 1,15 * *", "2026-03-15", true},
+		{"0 0 1,15 * *", "2026-03-16", false},
+		{"0 0 */10 * *", "2026-03-21", true},
+		{"0 0 */10 * *", "2026-03-20", false},
+		{"0 0 1 1-3 *", "2026-04-01", false},
+		// A restricted day of month and day of week match either
+		{"0 0 13 * 5", "2026-03-06", true},
+		{"0 0 13 * 5", "2026-03-13", true},
+		{"0 0 13 * 5", "2026-03-12", false},
+	}
+	for _, tt := range tests {
+		match, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := match(day(t, tt.date)); got != tt.match {
+			t.Errorf("parseCron(%q) on %s = %t, want %t", tt.expr, tt.date, got, tt.match)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"0 0 * *",
+		"0 0 * * * *",
+		"60 0 * * *",
+		"0 24 * * *",
+		"0 0 0 * *",
+		"0 0 * 13 *",
+		"0 0 * * 8",
+		"0 0 5-1 * *",
+		"0 0 */0 * *",
+		"0 0 x * *",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	tests := []struct {
+		rule, base, today, want string
+	}{
+		{"daily", "2026-03-10", "2026-03-10", "2026-03-11"},
+		{"weekly", "2026-03-02", "2026-03-02", "2026-03-09"},
+		// A late task keeps its weekday
+		{"weekly", "2026-03-02", "2026-03-20", "2026-03-23"},
+		{"monthly", "2026-01-31", "2026-01-31", "2026-02-28"},
+		{"monthly", "2026-01-31", "2026-03-01", "2026-03-31"},
+		{"yearly", "2024-02-29", "2024-02-29", "2025-02-28"},
+		{"30d", "2026-01-01", "2026-01-01", "2026-01-31"},
+		{"2w", "2026-01-01", "2026-01-01", "2026-01-15"},
+		{"3m", "2026-01-15", "2026-01-15", "2026-04-15"},
+		{"0 9 * * 1-5", "2026-03-06", "2026-03-06", "2026-03-09"},
+		{"0 0 1,15 * *", "2026-03-01", "2026-03-01", "2026-03-15"},
+		{"0 0 13 * 5", "2026-03-01", "2026-03-01", "2026-03-06"},
+		// Cron rules skip to the first match from today
+		{"0 0 * * 1", "2026-01-05", "2026-03-10", "2026-03-16"},
+		{"0 0 29 2 *", "2026-03-01", "2026-03-01", "2028-02-29"},
+	}
+	for _, tt := range tests {
+		got, err := nextOccurrence(tt.rule, day(t, tt.base), day(t, tt.today))
+		if err != nil {
+			t.Errorf("nextOccurrence(%q, %s, %s): %v", tt.rule, tt.base, tt.today, err)
+			continue
+		}
+		if got.Format("2006-01-02") != tt.want {
+			t.Errorf("nextOccurrence(%q, %s, %s) = %s, want %s",
+				tt.rule, tt.base, tt.today, got.Format("2006-01-02"), tt.want)
+		}
+	}
+}
+
+func TestNextOccurrenceErrors(t *testing.T) {
+	base := day(t, "2026-03-01")
+	for _, rule := range []string{"fortnightly", "0d", "-2w", "+3d", "0 0 30 2 *", "0 0 31 4 *"} {
+		if _, err := nextOccurrence(rule, base, base); err == nil {
+			t.Errorf("nextOccurrence(%q) succeeded, want an error", rule)
+		}
+	}
+}
+
+func TestRepeatTask(t *testing.T) {
+	t.Setenv("TODO_DIR", t.TempDir())
+	now := day(t, "2026-03-10").Add(12 * time.Hour)
+	tests := []struct {
+		name string
+		task Task
+		want string
+	}{
+		{"from deadline", Task{ID: 1, Repeat: "weekly", Deadline: day(t, "2026-03-03")}, "2026-03-10"},
+		{"from done", Task{ID: 1, Repeat: "weekly", RepeatFrom: repeatFromDone, Deadline: day(t, "2026-03-03")}, "2026-03-17"},
+		{"from done interval", Task{ID: 1, Repeat: "30d", RepeatFrom: repeatFromDone, Deadline: day(t, "2026-01-01")}, "2026-04-09"},
+		{"without deadline", Task{ID: 1, Repeat: "daily"}, "2026-03-11"},
+		{"paused until", Task{ID: 1, Repeat: "weekly", Deadline: day(t, "2026-03-03"), Paused: true, PausedUntil: day(t, "2026-04-01")}, "2026-04-07"},
+	}
+	for _, tt := range tests {
+		tt.task.Title = "chore"
+		tt.task.Done = true
+		tasks, id, err := repeatTask([]Task{tt.task}, 1, now)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		next := findTask(tasks, id)
+		if next == nil {
+			t.Errorf("%s: no next occurrence added", tt.name)
+			continue
+		}
+		if got := next.Deadline.Format("2006-01-02"); got != tt.want || next.Deadline.Location() != time.UTC {
+			t.Errorf("%s: next deadline %v, want %s UTC", tt.name, next.Deadline, tt.want)
+		}
+		if next.Done || next.Repeat != tt.task.Repeat || next.RepeatFrom != tt.task.RepeatFrom || next.Paused != tt.task.Paused {
+			t.Errorf("%s: next occurrence %+v does not carry over the rule", tt.name, *next)
+		}
+	}
+}
+
+func TestRepeatTaskEnded(t *testing.T) {
+	t.Setenv("TODO_DIR", t.TempDir())
+	task := Task{ID: 1, Title: "course", Done: true, Repeat: "FREQ=DAILY;UNTIL=20260310", Deadline: day(t, "2026-03-10")}
+	tasks, id, err := repeatTask([]Task{task}, 1, day(t, "2026-03-10"))
+	if err != nil || id != 0 || len(tasks) != 1 {
+		t.Errorf("repeatTask past UNTIL = %d tasks, id %d, %v; want no new task", len(tasks), id, err)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// remindPoll is how often "remind" checks the deadlines
+const remindPoll = time.Minute
+
+// defaultRemindBefore is the lead time when remind_before is not set
+const defaultRemindBefore = time.Hour
+
+// toastScript shows a Windows toast notification through PowerShell
+const toastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode('%s')) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode('%s')) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('todo').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+
+// notify shows a desktop notification with the platform's own tool
+func notify(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		quote := func(s string) string { return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"` }
+		cmd = exec.Command("osascript", "-e", "display notification "+quote(body)+" with title "+quote(title))
+	case "windows":
+		quote := func(s string) string { return strings.ReplaceAll(s, "'", "''") }
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", fmt.Sprintf(toastScript, quote(title), quote(body)))
+	default:
+		cmd = exec.Command("notify-send", "--app-name=todo", title, body)
+	}
+	return cmd.Run()
+}
+
+// alert shows a notification, reporting rather than failing when the
+// desktop can't show it
+func alert(title, body string) {
+	if err := notify(title, body); err != nil {
+		fmt.Printf("%sCould not show a notification: %v%s\n", yellow, err, reset)
+	}
+}
+
+// remindLead returns how long before a deadline to notify: the given
+// value, else remind_before from config, else an hour
+func remindLead(value string) (time.Duration, error) {
+	if value == "" {
+		value = config.RemindBefore
+	}
+	if value == "" {
+		return defaultRemindBefore, nil
+	}
+	lead, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lead time %q, use e.g. 30m or 2h", value)
+	}
+	return lead, nil
+}
+
+// parseLead reads a lead time: a Go duration such as "30m" or "2h", or a
+// number of days or weeks such as "7d" or "1w"
+func parseLead(text string) (time.Duration, error) {
+	if n, err := strconv.Atoi(strings.TrimRight(text, "dw")); err == nil && len(text) > 1 {
+		switch text[len(text)-1] {
+		case 'd':
+			return time.Duration(n) * 24 * time.Hour, nil
+		case 'w':
+			return time.Duration(n) * 7 * 24 * time.Hour, nil
+		}
+	}
+	lead, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lead time %q, use e.g. 2h, 1d or 1w", text)
+	}
+	return lead, nil
+}
+
+// escalationFor returns the escalation chain configured for a task's
+// priority, if there is one
+func (c Config) escalationFor(task Task) (EscalationConfig, bool) {
+	for level, chain := range c.Escalation {
+		if p, err := parsePriority(level); err == nil && p == task.Priority {
+			return chain, true
+		}
+	}
+	return EscalationConfig{}, false
+}
+
+// stage returns how far along the chain a task due at due is at now: the
+// number of leads reached, plus one for every overdue interval begun since
+// the deadline. It only grows, so each new stage is worth a notification.
+func (e EscalationConfig) stage(due, now time.Time) int {
+	stage := 0
+	for _, text := range e.Before {
+		if lead, err := parseLead(text); err == nil && !now.Before(due.Add(-lead)) {
+			stage++
+		}
+	}
+	if every, err := parseLead(e.Overdue); err == nil && every > 0 && !now.Before(due) {
+		stage += 1 + int(now.Sub(due)/every)
+	}
+	return stage
+}
+
+// roughDuration renders a duration to the minute, e.g. "2h30m", or in
+// whole days from two days up
+func roughDuration(d time.Duration) string {
+	if days := int(d / (24 * time.Hour)); days >= 2 {
+		return strconv.Itoa(days) + "d"
+	}
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
+
+// escalated is the last stage notified for a task, and for which deadline
+type escalated struct {
+	deadline time.Time
+	stage    int
+}
+
+// quiet reports whether now falls in the configured quiet hours
+func (q QuietHours) quiet(now time.Time) bool {
+	for _, day := range q.Days {
+		if d, err := parseWeekday(day); err == nil && now.Weekday() == d {
+			return true
+		}
+	}
+	if q.From == "" || q.To == "" {
+		return false
+	}
+	from, _ := parseClock(q.From)
+	to, _ := parseClock(q.To)
+	clock := now.Sub(dateOf(now))
+	if from <= to {
+		return clock >= from && clock < to
+	}
+	// The window wraps past midnight, e.g. 22:00 to 07:00
+	return clock >= from || clock < to
+}
+
+// dueSoon returns the unfinished tasks falling due within lead of now,
+// leaving out paused ones and those with an escalation chain
+func dueSoon(tasks []Task, lead time.Duration, now time.Time) []Task {
+	var soon []Task
+	for _, task := range tasks {
+		if _, escalates := config.escalationFor(task); escalates || task.Done || task.Deadline.IsZero() || task.paused(now) {
+			continue
+		}
+		if due := dueAt(task.Deadline); !now.Before(due.Add(-lead)) && now.Before(due) {
+			soon = append(soon, task)
+		}
+	}
+	return soon
+}
+
+// remind notifies about each task coming due within lead, once per
+// deadline, or along the escalation chain set for its priority. It reads
+// the store on every check, so tasks added or moved while it runs are
+// picked up; a snoozed task starts its chain again. Tasks covered by the
+// digest config wait for the next digest instead. Saved searches with
+// notify set alert about each task that starts matching them. Reminders
+// during quiet hours are held and sent as one digest when the quiet
+// hours end. With once set it checks a single time, for running from
+// cron, and quiet hours simply mute it.
+func remind(lead time.Duration, once bool) error {
+	notified := map[int]time.Time{}
+	stages := map[int]escalated{}
+	var held []string
+	lastDigest := latestDigest(config.Digest.Times, time.Now())
+	for {
+		tasks, err := loadTasks()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		quiet := config.QuietHours.quiet(now)
+		if !quiet && len(held) > 0 {
+			alert(fmt.Sprintf("%d reminders during quiet hours", len(held)), strings.Join(held, "\n"))
+			held = nil
+		}
+		if due := latestDigest(config.Digest.Times, now); due.After(lastDigest) {
+			lastDigest = due
+			if title, body := digest(tasks, lead, now); quiet {
+				fmt.Printf("%s %s (held for quiet hours)\n", now.Format("15:04"), title)
+				held = append(held, title)
+			} else if err := sendDigest(title, body); err != nil {
+				fmt.Printf("%sCould not send the digest: %v%s\n", yellow, err, reset)
+			}
+		}
+		send := func(task Task, body string) {
+			if quiet {
+				fmt.Printf("%s %s: %s (held for quiet hours)\n", now.Format("15:04"), task.Title, body)
+				held = append(held, task.Title+": "+body)
+				return
+			}
+			fmt.Printf("%s %s: %s\n", now.Format("15:04"), task.Title, body)
+			alert(task.Title, body)
+		}
+		for _, task := range dueSoon(tasks, lead, now) {
+			if config.Digest.batches(task) || notified[task.ID].Equal(task.Deadline) {
+				continue
+			}
+			notified[task.ID] = task.Deadline
+			send(task, "#"+strconv.Itoa(task.ID)+" is due in "+roughDuration(dueAt(task.Deadline).Sub(now)))
+		}
+		if err := notifySearches(tasks, send); err != nil {
+			fmt.Printf("%sCould not check saved searches: %v%s\n", yellow, err, reset)
+		}
+		for _, task := range tasks {
+			chain, escalates := config.escalationFor(task)
+			if !escalates || task.Done || task.Deadline.IsZero() || task.paused(now) || config.Digest.batches(task) {
+				continue
+			}
+			due := dueAt(task.Deadline)
+			stage := chain.stage(due, now)
+			if last := stages[task.ID]; stage == 0 || (last.deadline.Equal(task.Deadline) && stage <= last.stage) {
+				continue
+			}
+			stages[task.ID] = escalated{deadline: task.Deadline, stage: stage}
+			if now.Before(due) {
+				send(task, "#"+strconv.Itoa(task.ID)+" is due in "+roughDuration(due.Sub(now)))
+			} else {
+				send(task, "#"+strconv.Itoa(task.ID)+" is overdue by "+roughDuration(now.Sub(due)))
+			}
+		}
+		if once {
+			return nil
+		}
+		time.Sleep(remindPoll)
+	}
+}
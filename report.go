@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reportStart reads --since as an offset back from today, e.g. "7d" or
+// "2w", or as a date
+func reportStart(since string, now time.Time) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", since); err == nil {
+		return dateOf(date), nil
+	}
+	years, months, days, err := parseOffset(strings.TrimPrefix(since, "-"))
+	if err != nil {
+		return now, fmt.Errorf("invalid --since %q, use e.g. 7d, 2w or YYYY-MM-DD", since)
+	}
+	return logicalDate(now).AddDate(-years, -months, -days), nil
+}
+
+// groupTasks splits tasks by tag or project, keeping first-seen order
+// within each group. A task with several tags appears under each.
+func groupTasks(tasks []Task, by string) ([]string, map[string][]Task) {
+	groups := map[string][]Task{}
+	for _, task := range tasks {
+		keys := []string{task.Project}
+		if by == "tag" {
+			keys = task.Tags
+			if len(keys) == 0 {
+				keys = []string{""}
+			}
+		}
+		for _, key := range keys {
+			groups[key] = append(groups[key], task)
+		}
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	// Ungrouped tasks come last
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == "") != (names[j] == "") {
+			return names[j] == ""
+		}
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names, groups
+}
+
+// summaryReport renders the tasks completed and added since from and the
+// ones still overdue, as Markdown for a standup or status email.
+// Completions are found by completedTasks.
+func summaryReport(tasks, archived []Task, ops []Operation, from, now time.Time, by string) string {
+	all := append(append([]Task(nil), tasks...), archived...)
+	start := dayStart(from)
+	completed := completedTasks(all, ops, start, now)
+	var added, overdue []Task
+	for _, task := range all {
+		if !task.CreatedAt.Before(start) {
+			added = append(added, task)
+		}
+	}
+	for _, task := range tasks {
+		if isOverdue(task, now) {
+			task.Title += " (due " + task.Deadline.Format("2006-01-02") + ")"
+			overdue = append(overdue, task)
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Report for %s to %s\n\n", from.Format("2006-01-02"), logicalDate(now).Format("2006-01-02"))
+	writeList := func(items []Task) {
+		for _, task := range items {
+			fmt.Fprintf(&out, "- %s\n", task.Title)
+		}
+	}
+	writeSection := func(heading string, items []Task) {
+		fmt.Fprintf(&out, "*%s (%d)*\n", heading, len(items))
+		switch {
+		case len(items) == 0:
+			out.WriteString("- None\n")
+		case by == "":
+			writeList(items)
+		default:
+			names, groups := groupTasks(items, by)
+			for _, name := range names {
+				label := name
+				if name == "" {
+					label = "No " + by
+				} else if by == "tag" {
+					label = "+" + name
+				}
+				fmt.Fprintf(&out, "%s:\n", label)
+				writeList(groups[name])
+			}
+		}
+		out.WriteString("\n")
+	}
+	writeSection("Completed", completed)
+	writeSection("Added", added)
+	writeSection("Still overdue", overdue)
+	return strings.TrimSuffix(out.String(), "\n")
+}
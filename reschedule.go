@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+)
+
+// rescheduleTasks moves the deadline of every task matching the filter,
+// previewing the changes and asking for confirmation unless confirmed
+func rescheduleTasks(tasks []Task, expr, to string, confirmed bool, in io.Reader, now time.Time) ([]Task, error) {
+	filter, err := parseFilter(expr, now)
+	if err != nil {
+		return tasks, err
+	}
+	newDeadline, err := parseDateSpec(to, now)
+	if err != nil {
+		return tasks, err
+	}
+
+	matched := filter.apply(tasks)
+	if len(matched) == 0 {
+		fmt.Println(yellow + "No matching tasks" + reset)
+		return tasks, nil
+	}
+	for _, task := range matched {
+		old := "none"
+		if !task.Deadline.IsZero() {
+			old = task.Deadline.Format("2006-01-02")
+		}
+		fmt.Printf("#%d: %s  %s -> %s\n", task.ID, task.Title, old, newDeadline(task.Deadline).Format("2006-01-02"))
+	}
+	if !confirmed && !confirm(bufio.NewReader(in), fmt.Sprintf("Reschedule %d tasks?", len(matched))) {
+		fmt.Println(yellow + "Nothing changed" + reset)
+		return tasks, nil
+	}
+
+	for _, task := range matched {
+		t := findTask(tasks, task.ID)
+		t.Deadline = newDeadline(t.Deadline)
+	}
+	fmt.Printf("%sRescheduled %d tasks%s\n", green, len(matched), reset)
+	return tasks, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reviewPeriod returns the start and end of the month or quarter containing
+// now, or the one before it when previous is set
+func reviewPeriod(period string, previous bool, now time.Time) (time.Time, time.Time, error) {
+	today := logicalDate(now)
+	var months int
+	switch period {
+	case "month":
+		months = 1
+	case "quarter":
+		months = 3
+	default:
+		return today, today, fmt.Errorf("unknown period %q, use month or quarter", period)
+	}
+	first := (int(today.Month()) - 1) / months * months
+	from := time.Date(today.Year(), time.Month(first+1), 1, 0, 0, 0, 0, time.Local)
+	if previous {
+		from = from.AddDate(0, -months, 0)
+	}
+	return from, from.AddDate(0, months, 0), nil
+}
+
+// periodName labels a review period, e.g. "October 2026" or "Q4 2026"
+func periodName(period string, from time.Time) string {
+	if period == "quarter" {
+		return fmt.Sprintf("Q%d %d", (int(from.Month())-1)/3+1, from.Year())
+	}
+	return from.Format("January 2006")
+}
+
+// abandonedTasks returns the unfinished tasks deleted in [from, to)
+func abandonedTasks(ops []Operation, from, to time.Time) []Task {
+	var abandoned []Task
+	for _, op := range ops {
+		if op.Command != "delete" || op.Time.Before(from) || !op.Time.Before(to) {
+			continue
+		}
+		for _, task := range op.Before {
+			if !task.Done && findTask(op.After, task.ID) == nil {
+				abandoned = append(abandoned, task)
+			}
+		}
+	}
+	return abandoned
+}
+
+// reviewReport renders a Markdown retrospective of a month or quarter:
+// what got done, what was dropped or slipped, how each project moved and
+// which tags the finished work carried
+func reviewReport(tasks, archived []Task, ops []Operation, projects []Project, period string, from, to, now time.Time) string {
+	all := append(append([]Task(nil), tasks...), archived...)
+	completed := completedTasks(all, ops, dayStart(from), dayStart(to))
+	abandoned := abandonedTasks(ops, dayStart(from), dayStart(to))
+	var slipped []Task
+	for _, task := range tasks {
+		if !task.Done && !task.Deadline.IsZero() && !dateOf(task.Deadline).Before(from) &&
+			dateOf(task.Deadline).Before(to) && isOverdue(task, now) {
+			slipped = append(slipped, task)
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "# Review: %s\n\n", periodName(period, from))
+	fmt.Fprintf(&out, "%s to %s\n\n", from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02"))
+	fmt.Fprintf(&out, "- Completed: %d\n- Abandoned: %d\n- Slipped: %d\n\n", len(completed), len(abandoned), len(slipped))
+
+	writeSection := func(heading string, items []Task, detail func(Task) string) {
+		fmt.Fprintf(&out, "## %s\n\n", heading)
+		for _, task := range items {
+			fmt.Fprintf(&out, "- %s%s\n", task.Title, detail(task))
+		}
+		if len(items) == 0 {
+			out.WriteString("- None\n")
+		}
+		out.WriteString("\n")
+	}
+	noDetail := func(Task) string { return "" }
+	writeSection("Completed", completed, noDetail)
+	writeSection("Abandoned", abandoned, noDetail)
+	writeSection("Slipped", slipped, func(task Task) string {
+		return " (due " + task.Deadline.Format("2006-01-02") + ")"
+	})
+
+	out.WriteString("## Goals\n\n")
+	goals := 0
+	for _, project := range projects {
+		owned := projectTasks(all, project.Name)
+		closedNow := project.Closed && !project.ClosedAt.Before(dayStart(from)) && project.ClosedAt.Before(dayStart(to))
+		if project.Closed && !closedNow {
+			continue
+		}
+		done := 0
+		for _, task := range owned {
+			if task.Done {
+				done++
+			}
+		}
+		fmt.Fprintf(&out, "- %s: %d of %d tasks done", project.Name, done, len(owned))
+		if closedNow {
+			fmt.Fprintf(&out, ", closed %s", project.ClosedAt.Format("2006-01-02"))
+		}
+		out.WriteString("\n")
+		goals++
+	}
+	if goals == 0 {
+		out.WriteString("- None\n")
+	}
+	out.WriteString("\n")
+
+	out.WriteString("## Effort by tag\n\n")
+	effort := map[string]int{}
+	for _, task := range completed {
+		for _, tag := range task.Tags {
+			effort[tag]++
+		}
+		if len(task.Tags) == 0 {
+			effort[""]++
+		}
+	}
+	tags := make([]string, 0, len(effort))
+	for tag := range effort {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if effort[tags[i]] != effort[tags[j]] {
+			return effort[tags[i]] > effort[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	for _, tag := range tags {
+		label := "+" + tag
+		if tag == "" {
+			label = "untagged"
+		}
+		fmt.Fprintf(&out, "- %s: %d completed\n", label, effort[tag])
+	}
+	if len(tags) == 0 {
+		out.WriteString("- None\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rpcRequest is an incoming JSON-RPC 2.0 request or notification
+type rpcRequest struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+}
+
+// rpcError is the error object of a failed request
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// maxRPCMessage caps the size of a request body; the largest requests
+// carry a title and a deadline
+const maxRPCMessage = 1 << 20
+
+// rpcServer serves task operations to an editor plugin over stdio
+type rpcServer struct {
+	out     io.Writer
+	writeMu sync.Mutex
+	storeMu sync.Mutex
+	modTime time.Time
+}
+
+// readRPCMessage reads one message framed with a Content-Length header,
+// as in the Language Server Protocol
+func readRPCMessage(reader *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("bad Content-Length header")
+	}
+	if length < 0 || length > maxRPCMessage {
+		return nil, fmt.Errorf("Content-Length %d is out of range", length)
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(reader, body)
+	return body, err
+}
+
+// send writes one framed message
+func (s *rpcServer) send(msg map[string]interface{}) {
+	msg["jsonrpc"] = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// notifyChanged tells the client the task list has changed
+func (s *rpcServer) notifyChanged(tasks []Task) {
+	s.send(map[string]interface{}{"method": "tasks/changed", "params": map[string]interface{}{"tasks": tasks}})
+}
+
+// mutate applies a change to the stored tasks, journals it and notifies
+// the client
+func (s *rpcServer) mutate(command string, change func([]Task) ([]Task, error)) ([]Task, error) {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	tasks, err := loadTasks()
+	if err != nil {
+		return nil, err
+	}
+	original := copyTasks(tasks)
+	if tasks, err = change(tasks); err != nil {
+		return nil, err
+	}
+	if !tasksChanged(original, tasks) {
+		return tasks, nil
+	}
+	if err := saveTasks(tasks); err != nil {
+		return nil, err
+	}
+	if err := recordOperation(command, original, tasks); err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(dataPath("tasks.txt")); err == nil {
+		s.modTime = info.ModTime()
+	}
+	s.notifyChanged(tasks)
+	return tasks, nil
+}
+
+// watch notifies the client when another process changes the tasks
+func (s *rpcServer) watch() {
+	for range time.Tick(time.Second) {
+		info, err := os.Stat(dataPath("tasks.txt"))
+		if err != nil {
+			continue
+		}
+		s.storeMu.Lock()
+		changed := !info.ModTime().Equal(s.modTime)
+		s.modTime = info.ModTime()
+		s.storeMu.Unlock()
+		if changed {
+			if tasks, err := loadTasks(); err == nil {
+				s.notifyChanged(tasks)
+			}
+		}
+	}
+}
+
+// call runs a single method
+func (s *rpcServer) call(method string, params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		ID       int    `json:"id"`
+		Title    string `json:"title"`
+		Deadline string `json:"deadline"`
+		Project  string `json:"project"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+	}
+	byID := func(op func([]Task, int) ([]Task, bool)) func([]Task) ([]Task, error) {
+		return func(tasks []Task) ([]Task, error) {
+			tasks, found := op(tasks, p.ID)
+			if !found {
+				return tasks, fmt.Errorf("task #%d not found", p.ID)
+			}
+			return tasks, nil
+		}
+	}
+
+	var result interface{}
+	var err error
+	switch method {
+	case "tasks/list":
+		s.storeMu.Lock()
+		result, err = loadTasks()
+		s.storeMu.Unlock()
+	case "tasks/add":
+		if p.Title == "" {
+			return nil, &rpcError{rpcInvalidParams, "title is required"}
+		}
+		// Like the add command, tasks go in the default project unless
+		// another one is given
+		name := config.DefaultProject
+		if p.Project != "" {
+			name = p.Project
+		}
+		var project *Project
+		if name != "" {
+			if project, err = openProject(name); err != nil {
+				return nil, &rpcError{rpcInvalidParams, err.Error()}
+			}
+		}
+		var newID int
+		_, err = s.mutate("add", func(tasks []Task) ([]Task, error) {
+			tasks, newID = addTask(tasks, p.Title, p.Deadline)
+			if project != nil {
+				project.join(findTask(tasks, newID))
+			}
+			return tasks, nil
+		})
+		result = map[string]int{"id": newID}
+	case "tasks/done":
+		_, err = s.mutate("done", func(tasks []Task) ([]Task, error) {
+			tasks, _, err := completeTask(tasks, p.ID, time.Now())
+			return tasks, err
+		})
+	case "tasks/delete":
+		_, err = s.mutate("delete", byID(deleteTask))
+	default:
+		return nil, &rpcError{rpcMethodNotFound, "unknown method " + method}
+	}
+	if err != nil {
+		return nil, &rpcError{rpcServerError, err.Error()}
+	}
+	return result, nil
+}
+
+// serveRPC handles requests from in until it closes or "exit" is received
+func serveRPC(in io.Reader, out io.Writer) error {
+	s := &rpcServer{out: out}
+	if info, err := os.Stat(dataPath("tasks.txt")); err == nil {
+		s.modTime = info.ModTime()
+	}
+	go s.watch()
+
+	reader := bufio.NewReader(in)
+	for {
+		body, err := readRPCMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.send(map[string]interface{}{"id": nil, "error": rpcError{rpcParseError, err.Error()}})
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+		result, rpcErr := s.call(req.Method, req.Params)
+		if req.ID == nil {
+			continue // notifications get no reply
+		}
+		if rpcErr != nil {
+			s.send(map[string]interface{}{"id": req.ID, "error": rpcErr})
+		} else {
+			s.send(map[string]interface{}{"id": req.ID, "result": result})
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errRepeatEnded means a recurrence rule has no occurrences left
+var errRepeatEnded = fmt.Errorf("the rule has no more occurrences")
+
+// rruleWeekdays are the iCalendar day names in time.Weekday order
+var rruleWeekdays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// rruleDay is a BYDAY entry: a weekday, optionally the nth (or nth from
+// last, when negative) of its month or year
+type rruleDay struct {
+	Nth     int
+	Weekday time.Weekday
+}
+
+// rrule is a parsed iCalendar recurrence rule. Deadlines are dates, so
+// parts below a day (BYHOUR and the like) are not accepted.
+type rrule struct {
+	Freq      string
+	Interval  int
+	Until     time.Time
+	Months    map[int]bool
+	MonthDays []int
+	Days      []rruleDay
+}
+
+// isRRule reports whether a repeat rule is written as an RRULE
+func isRRule(rule string) bool {
+	upper := strings.ToUpper(rule)
+	return strings.HasPrefix(upper, "RRULE:") || strings.HasPrefix(upper, "FREQ=")
+}
+
+// parseRRule reads an RRULE such as "FREQ=MONTHLY;BYDAY=-1FR", with or
+// without the "RRULE:" prefix
+func parseRRule(text string) (rrule, error) {
+	r := rrule{Interval: 1}
+	body := strings.ToUpper(strings.TrimSpace(text))
+	body = strings.TrimPrefix(body, "RRULE:")
+	for _, part := range strings.Split(body, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return r, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.Freq = value
+			default:
+				return r, fmt.Errorf("RRULE frequency %s is not supported, use DAILY, WEEKLY, MONTHLY or YEARLY", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("invalid RRULE interval %q", value)
+			}
+			r.Interval = n
+		case "UNTIL":
+			// A date-time UNTIL is cut to its date
+			date := value
+			if len(date) > 8 {
+				date = date[:8]
+			}
+			until, err := time.ParseInLocation("20060102", date, time.Local)
+			if err != nil {
+				return r, fmt.Errorf("invalid RRULE until %q", value)
+			}
+			r.Until = until
+		case "BYMONTH":
+			r.Months = map[int]bool{}
+			for _, item := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(item)
+				if err != nil || n < 1 || n > 12 {
+					return r, fmt.Errorf("invalid RRULE month %q", item)
+				}
+				r.Months[n] = true
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(item)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return r, fmt.Errorf("invalid RRULE month day %q", item)
+				}
+				r.MonthDays = append(r.MonthDays, n)
+			}
+		case "BYDAY":
+			for _, item := range strings.Split(value, ",") {
+				if len(item) < 2 {
+					return r, fmt.Errorf("invalid RRULE day %q", item)
+				}
+				day := rruleDay{Weekday: -1}
+				for i, name := range rruleWeekdays {
+					if strings.HasSuffix(item, name) {
+						day.Weekday = time.Weekday(i)
+					}
+				}
+				if day.Weekday < 0 {
+					return r, fmt.Errorf("invalid RRULE day %q", item)
+				}
+				if nth := strings.TrimPrefix(item[:len(item)-2], "+"); nth != "" {
+					n, err := strconv.Atoi(nth)
+					if err != nil || n == 0 || n < -53 || n > 53 {
+						return r, fmt.Errorf("invalid RRULE day %q", item)
+					}
+					day.Nth = n
+				}
+				r.Days = append(r.Days, day)
+			}
+		case "WKST":
+			// Weeks are counted from Monday, as the default WKST is
+			if value != "MO" {
+				return r, fmt.Errorf("RRULE WKST=%s is not supported, weeks start on MO", value)
+			}
+		default:
+			return r, fmt.Errorf("RRULE part %s is not supported", key)
+		}
+	}
+	if r.Freq == "" {
+		return r, fmt.Errorf("RRULE needs a FREQ")
+	}
+	for _, day := range r.Days {
+		if day.Nth != 0 && r.Freq != "MONTHLY" && r.Freq != "YEARLY" {
+			return r, fmt.Errorf("numbered BYDAY entries need FREQ=MONTHLY or YEARLY")
+		}
+	}
+	return r, nil
+}
+
+// period returns which interval-sized period since base date falls in,
+// counted in the rule's frequency
+func (r rrule) period(base, date time.Time) int {
+	switch r.Freq {
+	case "WEEKLY":
+		monday := func(t time.Time) time.Time { return t.AddDate(0, 0, -(int(t.Weekday())+6)%7) }
+		return int(monday(date).Sub(monday(base)).Hours()+12) / (24 * 7)
+	case "MONTHLY":
+		return (date.Year()-base.Year())*12 + int(date.Month()) - int(base.Month())
+	case "YEARLY":
+		return date.Year() - base.Year()
+	}
+	return int(date.Sub(base).Hours()+12) / 24
+}
+
+// matches reports whether date is an occurrence of the rule anchored at
+// base. Without BY parts the anchor's weekday, day of month or date is
+// kept, as iCalendar takes them from DTSTART.
+func (r rrule) matches(base, date time.Time) bool {
+	if r.period(base, date)%r.Interval != 0 {
+		return false
+	}
+	if r.Months != nil && !r.Months[int(date.Month())] {
+		return false
+	}
+	last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	if len(r.MonthDays) > 0 {
+		found := false
+		for _, n := range r.MonthDays {
+			found = found || n == date.Day() || (n < 0 && last+n+1 == date.Day())
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.Days) > 0 {
+		found := false
+		for _, day := range r.Days {
+			found = found || r.nthMatches(day, date, last)
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.Days) == 0 && len(r.MonthDays) == 0 {
+		switch r.Freq {
+		case "WEEKLY":
+			return date.Weekday() == base.Weekday()
+		case "MONTHLY":
+			return date.Day() == base.Day()
+		case "YEARLY":
+			return (r.Months != nil || date.Month() == base.Month()) && date.Day() == base.Day()
+		}
+	}
+	return true
+}
+
+// nthMatches reports whether date is the BYDAY entry's weekday and, when
+// it is numbered, the right one of its month, or of its year for yearly
+// rules without BYMONTH
+func (r rrule) nthMatches(day rruleDay, date time.Time, last int) bool {
+	if date.Weekday() != day.Weekday {
+		return false
+	}
+	if day.Nth == 0 {
+		return true
+	}
+	index, length := date.Day(), last
+	if r.Freq == "YEARLY" && r.Months == nil {
+		index = date.YearDay()
+		length = time.Date(date.Year(), 12, 31, 0, 0, 0, 0, date.Location()).YearDay()
+	}
+	if day.Nth > 0 {
+		return (index-1)/7+1 == day.Nth
+	}
+	return (length-index)/7+1 == -day.Nth
+}
+
+// next returns the first occurrence after base that is not before today
+func (r rrule) next(base, today time.Time) (time.Time, error) {
+	from := base
+	if yesterday := today.AddDate(0, 0, -1); from.Before(yesterday) {
+		from = yesterday
+	}
+	limit := cronSearchDays * r.Interval
+	for i := 1; i <= limit; i++ {
+		date := from.AddDate(0, 0, i)
+		if !r.Until.IsZero() && date.After(r.Until) {
+			return base, errRepeatEnded
+		}
+		if r.matches(base, date) {
+			return date, nil
+		}
+	}
+	return base, fmt.Errorf("RRULE never matches")
+}
+
+// rruleFor returns the RRULE, without its "RRULE:" prefix, that describes
+// a repeat rule in iCalendar files, or "" when the rule can't be written
+// as one: cron expressions restricting both the day of month and the day
+// of week match either, which RRULE cannot say.
+func rruleFor(rule string) string {
+	switch {
+	case rule == "daily", rule == "weekly", rule == "monthly", rule == "yearly":
+		return "FREQ=" + strings.ToUpper(rule)
+	case isRRule(rule):
+		return strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	}
+	fields := strings.Fields(rule)
+	if len(fields) != 5 || (fields[2] != "*" && fields[4] != "*") {
+		return ""
+	}
+	parts := []string{"FREQ=DAILY"}
+	list := func(field string, min, max int, name func(int) string) string {
+		allowed, err := parseCronField(field, min, max)
+		if err != nil {
+			return ""
+		}
+		var values []int
+		for v := range allowed {
+			values = append(values, v)
+		}
+		sort.Ints(values)
+		var names []string
+		seen := map[string]bool{}
+		for _, v := range values {
+			if n := name(v); !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+		return strings.Join(names, ",")
+	}
+	if fields[3] != "*" {
+		parts = append(parts, "BYMONTH="+list(fields[3], 1, 12, strconv.Itoa))
+	}
+	if fields[2] != "*" {
+		parts = append(parts, "BYMONTHDAY="+list(fields[2], 1, 31, strconv.Itoa))
+	}
+	if fields[4] != "*" {
+		parts = append(parts, "BYDAY="+list(fields[4], 0, 7, func(v int) string { return rruleWeekdays[v%7] }))
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestRRuleNext(t *testing.T) {
+	tests := []struct {
+		rule, base, today, want string
+	}{
+		{"FREQ=DAILY", "2026-03-10", "2026-03-10", "2026-03-11"},
+		{"FREQ=DAILY;INTERVAL=3", "2026-03-10", "2026-03-10", "2026-03-13"},
+		{"FREQ=WEEKLY", "2026-03-02", "2026-03-02", "2026-03-09"},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "2026-03-02", "2026-03-02", "2026-03-05"},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "2026-03-05", "2026-03-05", "2026-03-16"},
+		{"FREQ=MONTHLY", "2026-01-15", "2026-01-15", "2026-02-15"},
+		// The last Friday of the month
+		{"FREQ=MONTHLY;BYDAY=-1FR", "2026-01-30", "2026-01-30", "2026-02-27"},
+		{"FREQ=MONTHLY;BYDAY=+2TU", "2026-03-01", "2026-03-01", "2026-03-10"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "2026-01-31", "2026-01-31", "2026-02-28"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,15", "2026-03-01", "2026-03-01", "2026-03-15"},
+		// Thanksgiving
+		{"FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "2025-11-27", "2025-11-27", "2026-11-26"},
+		{"FREQ=YEARLY", "2026-03-10", "2026-03-10", "2027-03-10"},
+		{"freq=daily;until=20261231", "2026-03-10", "2026-03-10", "2026-03-11"},
+		// Occurrences missed while overdue are skipped
+		{"FREQ=WEEKLY;BYDAY=FR", "2026-01-02", "2026-03-10", "2026-03-13"},
+	}
+	for _, tt := range tests {
+		got, err := nextOccurrence(tt.rule, day(t, tt.base), day(t, tt.today))
+		if err != nil {
+			t.Errorf("nextOccurrence(%q, %s, %s): %v", tt.rule, tt.base, tt.today, err)
+			continue
+		}
+		if got.Format("2006-01-02") != tt.want {
+			t.Errorf("nextOccurrence(%q, %s, %s) = %s, want %s",
+				tt.rule, tt.base, tt.today, got.Format("2006-01-02"), tt.want)
+		}
+	}
+}
+
+func TestRRuleEnded(t *testing.T) {
+	base := day(t, "2026-03-10")
+	if _, err := nextOccurrence("FREQ=DAILY;UNTIL=20260310T235959Z", base, base); err != errRepeatEnded {
+		t.Errorf("nextOccurrence past UNTIL: got %v, want errRepeatEnded", err)
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	for _, rule := range []string{
+		"FREQ=HOURLY",
+		"INTERVAL=2",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=WEEKLY;BYDAY=2MO",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=WEEKLY;WKST=SU",
+		"FREQ=DAILY;UNTIL=soon",
+		"FREQ",
+	} {
+		if _, err := parseRRule(rule); err == nil {
+			t.Errorf("parseRRule(%q) succeeded, want an error", rule)
+		}
+	}
+}
+
+func TestRRuleFor(t *testing.T) {
+	tests := []struct {
+		rule, want string
+	}{
+		{"weekly", "FREQ=WEEKLY"},
+		{"rrule:freq=monthly;byday=-1fr", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"0 9 * * 1-5", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR"},
+		{"0 0 * * 0,7", "FREQ=DAILY;BYDAY=SU"},
+		{"0 0 1,15 * *", "FREQ=DAILY;BYMONTHDAY=1,15"},
+		{"0 0 1 1,7 *", "FREQ=DAILY;BYMONTH=1,7;BYMONTHDAY=1"},
+		// Day of month or day of week cannot be written as an RRULE
+		{"0 0 13 * 5", ""},
+	}
+	for _, tt := range tests {
+		if got := rruleFor(tt.rule); got != tt.want {
+			t.Errorf("rruleFor(%q) = %q, want %q", tt.rule, got, tt.want)
+		}
+	}
+}
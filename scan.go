@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// codeComment is a TODO or FIXME comment found in a source file
+type codeComment struct {
+	File  string // absolute path
+	Line  int    // one-based
+	Title string
+}
+
+var todoPattern = regexp.MustCompile(`\b(TODO|FIXME)\b(?:\([^)]*\))?[:\s]*(.*)$`)
+
+// skippedDirs are never descended into when scanning
+var skippedDirs = map[string]bool{"node_modules": true, "vendor": true, "target": true, "dist": true}
+
+// ownFiles returns the absolute paths of this tool's data files, which
+// hold task titles and would otherwise be picked up as comments. Besides
+// the backed up files these are the ones rebuilt or re-fetched as needed.
+func ownFiles() map[string]bool {
+	own := map[string]bool{}
+	for _, name := range append([]string{"targets.json", "tasks.sha256", "mstodo-token.json", "gcal-token.json"}, backupFiles...) {
+		if path, err := filepath.Abs(dataPath(name)); err == nil {
+			own[path] = true
+		}
+	}
+	return own
+}
+
+// scanComments finds every TODO/FIXME comment in the text files under root
+func scanComments(root string) ([]codeComment, error) {
+	var comments []codeComment
+	own := ownFiles()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || skippedDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if abs, err := filepath.Abs(path); err == nil && own[abs] {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || !info.Mode().IsRegular() || info.Size() > 1<<20 {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		head := data
+		if len(head) > 8000 {
+			head = head[:8000]
+		}
+		if bytes.IndexByte(head, 0) >= 0 {
+			return nil // binary file
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			m := todoPattern.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(m[2]), "*/"))
+			if text == "" {
+				text = "(no description)"
+			}
+			comments = append(comments, codeComment{File: path, Line: i + 1, Title: m[1] + ": " + text})
+		}
+		return nil
+	})
+	return comments, err
+}
+
+// commentRemote refers to a comment by file and line
+func commentRemote(c codeComment) Remote {
+	return Remote{
+		Source: "code",
+		ID:     c.File + ":" + strconv.Itoa(c.Line),
+		URL:    fileURL(c.File),
+	}
+}
+
+// remoteFile returns the file part of a "code" remote ID
+func remoteFile(remote Remote) string {
+	if i := strings.LastIndex(remote.ID, ":"); i >= 0 {
+		return remote.ID[:i]
+	}
+	return remote.ID
+}
+
+// scanCode turns the TODO/FIXME comments under dir into tasks. Comments
+// are matched to existing tasks by file and text, so tasks follow moved
+// lines, and tasks whose comment has disappeared are completed like "done"
+// would, which leaves tasks with open subtasks open.
+func scanCode(tasks []Task, dir string) ([]Task, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return tasks, err
+	}
+	comments, err := scanComments(root)
+	if err != nil {
+		return tasks, err
+	}
+
+	matched := map[int]bool{}
+	var added, closed int
+	for _, c := range comments {
+		var task *Task
+		for i := range tasks {
+			t := &tasks[i]
+			if matched[t.ID] || t.Done || len(t.Remotes) != 1 || t.Remotes[0].Source != "code" {
+				continue
+			}
+			if remoteFile(t.Remotes[0]) == c.File && t.Title == c.Title {
+				task = t
+				break
+			}
+		}
+		if task == nil {
+			var id int
+			tasks, id = addTask(tasks, c.Title, "")
+			task = findTask(tasks, id)
+			added++
+		}
+		task.Remotes = []Remote{commentRemote(c)}
+		matched[task.ID] = true
+	}
+
+	prefix := root + string(filepath.Separator)
+	var gone []int
+	for _, t := range tasks {
+		if matched[t.ID] || t.Done || len(t.Remotes) != 1 || t.Remotes[0].Source != "code" {
+			continue
+		}
+		if strings.HasPrefix(remoteFile(t.Remotes[0]), prefix) {
+			gone = append(gone, t.ID)
+		}
+	}
+	for _, id := range gone {
+		var done bool
+		if tasks, done = completeRemote(tasks, id); done {
+			closed++
+		}
+	}
+	fmt.Printf("%sFound %d comments: %d new tasks, %d tasks done%s\n", green, len(comments), added, closed, reset)
+	return tasks, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// How well a task matched a search, best first
+const (
+	matchExact = iota
+	matchPrefix
+	matchSubstring
+	matchNotes
+	matchFuzzy
+)
+
+// searchLimit is how many results search shows without --all
+const searchLimit = 10
+
+// highlight marks the matched part of a title; bold and underlined so it
+// reads the same with the accessible palette
+const highlight = "\033[1;4m"
+
+// searchHit is a task found by search, how it matched and which runes of
+// its title matched
+type searchHit struct {
+	Task  Task
+	Kind  int
+	Runes []int
+}
+
+// foldRunes lowercases s rune by rune, so indexes match the original
+func foldRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// indexRunes returns where needle first occurs in haystack, or -1
+func indexRunes(haystack, needle []rune) int {
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if string(haystack[i:i+len(needle)]) == string(needle) {
+			return i
+		}
+	}
+	return -1
+}
+
+// fuzzyRunes returns the positions of needle's letters found in order in
+// haystack, each as early as possible, or nil when some are missing.
+// Spaces in needle are ignored.
+func fuzzyRunes(haystack, needle []rune) []int {
+	var positions []int
+	at := 0
+	for _, r := range needle {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		for at < len(haystack) && haystack[at] != r {
+			at++
+		}
+		if at == len(haystack) {
+			return nil
+		}
+		positions = append(positions, at)
+		at++
+	}
+	return positions
+}
+
+// matchTask reports how a task matches query. Whole-word matches of the
+// title rank first, then prefixes, then text anywhere in the title, then
+// in the notes, then titles containing the query's letters in order.
+func matchTask(task Task, query string) (searchHit, bool) {
+	hit := searchHit{Task: task}
+	title, needle := foldRunes(task.Title), foldRunes(strings.TrimSpace(query))
+	if len(needle) == 0 {
+		return hit, false
+	}
+	if at := indexRunes(title, needle); at >= 0 {
+		switch {
+		case len(title) == len(needle):
+			hit.Kind = matchExact
+		case at == 0:
+			hit.Kind = matchPrefix
+		default:
+			hit.Kind = matchSubstring
+		}
+		for i := range needle {
+			hit.Runes = append(hit.Runes, at+i)
+		}
+		return hit, true
+	}
+	if indexRunes(foldRunes(task.Notes), needle) >= 0 {
+		hit.Kind = matchNotes
+		return hit, true
+	}
+	if positions := fuzzyRunes(title, needle); positions != nil {
+		hit.Kind = matchFuzzy
+		hit.Runes = positions
+		return hit, true
+	}
+	return hit, false
+}
+
+// spread is how far apart the matched runes of a hit lie
+func (h searchHit) spread() int {
+	if len(h.Runes) == 0 {
+		return 0
+	}
+	return h.Runes[len(h.Runes)-1] - h.Runes[0]
+}
+
+// searchTasks returns the tasks matching query, ignoring case, best
+// matches first. Among equal matches the tighter and earlier ones win,
+// then lower IDs.
+func searchTasks(tasks []Task, query string) []searchHit {
+	var hits []searchHit
+	for _, task := range tasks {
+		if hit, ok := matchTask(task, query); ok {
+			hits = append(hits, hit)
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.spread() != b.spread() {
+			return a.spread() < b.spread()
+		}
+		if len(a.Runes) > 0 && len(b.Runes) > 0 && a.Runes[0] != b.Runes[0] {
+			return a.Runes[0] < b.Runes[0]
+		}
+		return a.Task.ID < b.Task.ID
+	})
+	return hits
+}
+
+// highlightRunes marks the runes of title at the given positions. Runes
+// past the end, cut off when the title was shortened, are skipped.
+func highlightRunes(title string, positions []int) string {
+	marked := map[int]bool{}
+	for _, p := range positions {
+		marked[p] = true
+	}
+	var out strings.Builder
+	open := false
+	for i, r := range []rune(title) {
+		if marked[i] != open {
+			if open {
+				out.WriteString(reset)
+			} else {
+				out.WriteString(highlight)
+			}
+			open = !open
+		}
+		out.WriteRune(r)
+	}
+	if open {
+		out.WriteString(reset)
+	}
+	return out.String()
+}
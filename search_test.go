@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Title: "Call the plumber about the leak"},
+		{ID: 2, Title: "Plumber"},
+		{ID: 3, Title: "plumber invoice"},
+		{ID: 4, Title: "Fix sink", Notes: "The plumber said to use teflon tape"},
+		{ID: 5, Title: "Plan lunch with Uber"},
+		{ID: 6, Title: "Pay rent"},
+		{ID: 7, Title: "Ask a plumber"},
+	}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		// Exact, then prefix, then substring (earlier first), then notes,
+		// then letters in order, closest together first
+		{"plumber", []int{2, 3, 7, 1, 4}},
+		{"PLUMBER", []int{2, 3, 7, 1, 4}},
+		{"pay rent", []int{6}},
+		{"teflon", []int{4}},
+		{"pr", []int{6, 2, 3, 7, 1, 5}},
+		{"dentist", nil},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		var got []int
+		for _, hit := range searchTasks(tasks, tt.query) {
+			got = append(got, hit.Task.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("searchTasks(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTaskRunes(t *testing.T) {
+	tests := []struct {
+		title, query string
+		kind         int
+		runes        []int
+	}{
+		{"Café run", "café", matchPrefix, []int{0, 1, 2, 3}},
+		{"Grüße senden", "senden", matchSubstring, []int{6, 7, 8, 9, 10, 11}},
+		{"Pay rent", "pyrt", matchFuzzy, []int{0, 2, 4, 7}},
+		{"Pay rent", "p r", matchFuzzy, []int{0, 4}},
+	}
+	for _, tt := range tests {
+		hit, ok := matchTask(Task{Title: tt.title}, tt.query)
+		if !ok || hit.Kind != tt.kind || !reflect.DeepEqual(hit.Runes, tt.runes) {
+			t.Errorf("matchTask(%q, %q) = %v kind %d runes %v, want kind %d runes %v",
+				tt.title, tt.query, ok, hit.Kind, hit.Runes, tt.kind, tt.runes)
+		}
+	}
+}
+
+func TestHighlightRunes(t *testing.T) {
+	tests := []struct {
+		title     string
+		positions []int
+		want      string
+	}{
+		{"Pay rent", []int{0, 1, 2}, highlight + "Pay" + reset + " rent"},
+		{"Pay rent", []int{0, 4}, highlight + "P" + reset + "ay " + highlight + "r" + reset + "ent"},
+		{"Grüße", []int{2, 3}, "Gr" + highlight + "üß" + reset + "e"},
+		{"Pay", []int{1, 2, 5}, "P" + highlight + "ay" + reset},
+		{"Pay", nil, "Pay"},
+	}
+	for _, tt := range tests {
+		if got := highlightRunes(tt.title, tt.positions); got != tt.want {
+			t.Errorf("highlightRunes(%q, %v) = %q, want %q", tt.title, tt.positions, got, tt.want)
+		}
+	}
+}
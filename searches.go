@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SavedSearch is a named --where expression. With Notify set, remind
+// alerts whenever a task starts matching it.
+type SavedSearch struct {
+	Name   string `json:"name"`
+	Where  string `json:"where"`
+	Notify bool   `json:"notify,omitempty"`
+	// Matched holds the IDs matching at the last check, so only tasks
+	// that newly match are notified
+	Matched []int `json:"matched,omitempty"`
+}
+
+// loadSearches reads saved searches from searches.json file
+func loadSearches() ([]SavedSearch, error) {
+	file, err := os.ReadFile(dataPath("searches.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []SavedSearch{}, nil
+		}
+		return nil, err
+	}
+
+	var searches []SavedSearch
+	if err := json.Unmarshal(file, &searches); err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+// saveSearches writes saved searches to searches.json file
+func saveSearches(searches []SavedSearch) error {
+	data, err := json.MarshalIndent(searches, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dataPath("searches.json"), data, 0644)
+}
+
+// findSearch returns the saved search with the given name, ignoring case
+func findSearch(searches []SavedSearch, name string) *SavedSearch {
+	for i := range searches {
+		if strings.EqualFold(searches[i].Name, name) {
+			return &searches[i]
+		}
+	}
+	return nil
+}
+
+// savedSearchExpr compiles the expression of the named saved search
+func savedSearchExpr(name string) (expr, error) {
+	searches, err := loadSearches()
+	if err != nil {
+		return nil, err
+	}
+	search := findSearch(searches, name)
+	if search == nil {
+		return nil, fmt.Errorf("saved search %q not found, create it with \"searches add\"", name)
+	}
+	return compileExpr(search.Where)
+}
+
+// matchIDs returns the IDs of the tasks a search matches
+func (s SavedSearch) matchIDs(tasks []Task) ([]int, error) {
+	cond, err := compileExpr(s.Where)
+	if err != nil {
+		return nil, err
+	}
+	matched, err := whereTasks(tasks, cond)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for _, task := range matched {
+		ids = append(ids, task.ID)
+	}
+	return ids, nil
+}
+
+// newMatches returns the tasks matching a search now that did not match
+// at the last check, and records the current matches. It reports whether
+// they differ from the recorded ones.
+func (s *SavedSearch) newMatches(tasks []Task) ([]Task, bool, error) {
+	ids, err := s.matchIDs(tasks)
+	if err != nil {
+		return nil, false, err
+	}
+	before := map[int]bool{}
+	for _, id := range s.Matched {
+		before[id] = true
+	}
+	var fresh []Task
+	for _, id := range ids {
+		if !before[id] {
+			fresh = append(fresh, *findTask(tasks, id))
+		}
+	}
+	changed := len(fresh) > 0 || len(ids) != len(s.Matched)
+	s.Matched = ids
+	return fresh, changed, nil
+}
+
+// printSearches lists saved searches with how many tasks each matches
+func printSearches(searches []SavedSearch, tasks []Task) {
+	if len(searches) == 0 {
+		fmt.Println(yellow + "No saved searches" + reset)
+		return
+	}
+	for _, search := range searches {
+		count := "?"
+		if ids, err := search.matchIDs(tasks); err == nil {
+			count = fmt.Sprint(len(ids))
+		}
+		notify := ""
+		if search.Notify {
+			notify = " [notify]"
+		}
+		fmt.Printf("%s: %s (%s tasks)%s\n", search.Name, search.Where, count, notify)
+	}
+}
+
+// searchesCommand runs "searches add|remove|list"
+func searchesCommand(tasks []Task, args []string) error {
+	args, flags, err := parseFlags(args, "where")
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	searches, err := loadSearches()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		printSearches(searches, tasks)
+		return nil
+	case "add":
+		if len(args) < 2 || strings.TrimSpace(args[1]) == "" {
+			return fmt.Errorf("search name is required")
+		}
+		if !flags.has("where") {
+			return fmt.Errorf("--where is required")
+		}
+		if findSearch(searches, args[1]) != nil {
+			return fmt.Errorf("saved search %q already exists", args[1])
+		}
+		search := SavedSearch{Name: args[1], Where: flags.get("where"), Notify: flags.has("notify")}
+		// Tasks matching already are not news
+		if search.Matched, err = search.matchIDs(tasks); err != nil {
+			return err
+		}
+		if err := saveSearches(append(searches, search)); err != nil {
+			return err
+		}
+		fmt.Printf("%sSaved search %s matching %d tasks%s\n", green, search.Name, len(search.Matched), reset)
+		return nil
+	case "remove":
+		if len(args) < 2 {
+			return fmt.Errorf("search name is required")
+		}
+		search := findSearch(searches, args[1])
+		if search == nil {
+			return fmt.Errorf("saved search %q not found", args[1])
+		}
+		name := search.Name
+		var kept []SavedSearch
+		for _, s := range searches {
+			if s.Name != name {
+				kept = append(kept, s)
+			}
+		}
+		if err := saveSearches(kept); err != nil {
+			return err
+		}
+		fmt.Printf("%sRemoved saved search %s%s\n", yellow, name, reset)
+		return nil
+	default:
+		return fmt.Errorf("unknown searches command %q, use add, remove or list", args[0])
+	}
+}
+
+// notifySearches sends an alert for every task that newly matches a saved
+// search with notify set, and records the matches for the next check. A
+// search that fails to run is reported without holding up the others.
+func notifySearches(tasks []Task, send func(Task, string)) error {
+	searches, err := loadSearches()
+	if err != nil {
+		return err
+	}
+	changed := false
+	var failed error
+	for i := range searches {
+		if !searches[i].Notify {
+			continue
+		}
+		fresh, differ, err := searches[i].newMatches(tasks)
+		if err != nil {
+			if failed == nil {
+				failed = fmt.Errorf("%s: %v", searches[i].Name, err)
+			}
+			continue
+		}
+		for _, task := range fresh {
+			send(task, "#"+strconv.Itoa(task.ID)+" now matches "+searches[i].Name)
+		}
+		changed = changed || differ
+	}
+	if changed {
+		if err := saveSearches(searches); err != nil {
+			return err
+		}
+	}
+	return failed
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSavedSearchNewMatches(t *testing.T) {
+	search := SavedSearch{Name: "urgent", Where: `.priority == "high" && !.done`, Matched: []int{1}}
+	tasks := []Task{
+		{ID: 1, Title: "Already known", Priority: 3},
+		{ID: 2, Title: "Raised", Priority: 3},
+		{ID: 3, Title: "Low", Priority: 1},
+	}
+	fresh, changed, err := search.newMatches(tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fresh) != 1 || fresh[0].ID != 2 || !changed {
+		t.Errorf("newMatches = %v, %v, want task 2 and a change", fresh, changed)
+	}
+	if !reflect.DeepEqual(search.Matched, []int{1, 2}) {
+		t.Errorf("Matched = %v, want [1 2]", search.Matched)
+	}
+
+	// Nothing new on the next check
+	if fresh, changed, _ = search.newMatches(tasks); len(fresh) != 0 || changed {
+		t.Errorf("second check = %v, %v, want nothing", fresh, changed)
+	}
+
+	// A task that stops matching and matches again is news once more
+	tasks[1].Done = true
+	if fresh, changed, _ = search.newMatches(tasks); len(fresh) != 0 || !changed {
+		t.Errorf("after done = %v, %v, want only a change", fresh, changed)
+	}
+	tasks[1].Done = false
+	if fresh, _, _ = search.newMatches(tasks); len(fresh) != 1 || fresh[0].ID != 2 {
+		t.Errorf("after reopen = %v, want task 2", fresh)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// urlPattern finds web links written into task titles
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// printTaskDetails shows every field of a task along with its subtasks and
+// links to the items it was synced from
+func printTaskDetails(tasks []Task, task Task) {
+	status := red + "Not Done" + reset
+	if task.Done {
+		status = green + "Done" + reset
+	}
+	fmt.Printf("#%d: %s\n", task.ID, linkURLs(task.Title))
+	fmt.Printf("  Status:   %s\n", status)
+	if !task.Deadline.IsZero() {
+		fmt.Printf("  Deadline: %s%s\n", task.Deadline.Format("2006-01-02"), deadlineMarker(task, time.Now()))
+	}
+	if !task.CreatedAt.IsZero() {
+		fmt.Printf("  Created:  %s\n", task.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	if task.Done && !task.CompletedAt.IsZero() {
+		fmt.Printf("  Finished: %s\n", task.CompletedAt.Format("2006-01-02 15:04"))
+	}
+	if task.Repeat != "" {
+		after := ""
+		if task.RepeatFrom == repeatFromDone {
+			after = ", counted from when it is done"
+		}
+		fmt.Printf("  Repeats:  %s%s\n", task.Repeat, after)
+	}
+	if task.paused(time.Now()) {
+		until := "resumed"
+		if !task.PausedUntil.IsZero() {
+			until = task.PausedUntil.Format("2006-01-02")
+		}
+		fmt.Printf("  Paused:   until %s\n", until)
+	}
+	if task.Pinned {
+		fmt.Println("  Pinned:   yes")
+	}
+	if task.Pomodoros > 0 {
+		fmt.Printf("  Pomodoros: %d\n", task.Pomodoros)
+	}
+	if len(task.Sessions) > 0 {
+		running := ""
+		if task.tracking() {
+			running = ", running since " + task.Sessions[len(task.Sessions)-1].Start.Format("15:04")
+		}
+		fmt.Printf("  Tracked:  %s in %d sessions%s\n", formatSpent(task.timeSpent(time.Now())), len(task.Sessions), running)
+	}
+	if task.Priority != priorityNone {
+		fmt.Printf("  Priority: %s\n", priorityNames[task.Priority])
+	}
+	if len(task.Tags) > 0 {
+		fmt.Printf("  Tags:     %s\n", strings.Join(task.Tags, ", "))
+	}
+	if task.Project != "" {
+		fmt.Printf("  Project:  %s\n", task.Project)
+	}
+	if parent := findTask(tasks, task.ParentID); parent != nil {
+		fmt.Printf("  Parent:   #%d %s\n", parent.ID, parent.Title)
+	}
+	var children []string
+	for _, t := range tasks {
+		if t.ParentID == task.ID {
+			children = append(children, fmt.Sprintf("#%d", t.ID))
+		}
+	}
+	if len(children) > 0 {
+		fmt.Printf("  Subtasks: %s\n", strings.Join(children, ", "))
+	}
+	for _, remote := range task.Remotes {
+		fmt.Printf("  Link:     %s\n", remoteLabel(remote))
+	}
+	if task.Notes != "" {
+		fmt.Println("  Notes:")
+		for _, line := range renderMarkdown(task.Notes) {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+}
+
+// taskLink returns the best link for a task: the first synced item with a
+// URL, otherwise a URL in the title
+func taskLink(task Task) string {
+	for _, remote := range task.Remotes {
+		if remote.URL != "" {
+			return remote.URL
+		}
+	}
+	return urlPattern.FindString(task.Title)
+}
+
+// openURL opens a link with the desktop's default handler
+func openURL(link string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", link)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", link)
+	default:
+		cmd = exec.Command("xdg-open", link)
+	}
+	return cmd.Start()
+}
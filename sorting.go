@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sortTasks orders tasks by deadline, id, title, status or priority.
+// Tasks without a deadline go last when sorting by deadline, even when
+// reversed.
+func sortTasks(tasks []Task, key string, reverse bool) ([]Task, error) {
+	var less func(a, b Task) bool
+	switch key {
+	case "deadline":
+		less = func(a, b Task) bool { return a.Deadline.Before(b.Deadline) }
+	case "id":
+		less = func(a, b Task) bool { return a.ID < b.ID }
+	case "title":
+		less = func(a, b Task) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	case "status":
+		less = func(a, b Task) bool { return !a.Done && b.Done }
+	case "priority":
+		less = func(a, b Task) bool { return a.Priority > b.Priority }
+	default:
+		return nil, fmt.Errorf("unknown sort key %q, use deadline, id, title, status or priority", key)
+	}
+
+	sorted := append([]Task(nil), tasks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if key == "deadline" && a.Deadline.IsZero() != b.Deadline.IsZero() {
+			return b.Deadline.IsZero()
+		}
+		if reverse {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+	return sorted, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// blockedTags mark a task as blocked or waiting on someone else
+var blockedTags = []string{"blocked", "waiting"}
+
+// standupReport renders yesterday's completions, today's work, blocked
+// tasks and overdue ones as Markdown
+func standupReport(tasks []Task, ops []Operation, plan Plan, now time.Time) string {
+	today := logicalDate(now)
+	yesterday := today.AddDate(0, 0, -1)
+
+	var out strings.Builder
+	writeSection := func(heading string, items []Task) {
+		fmt.Fprintf(&out, "*%s*\n", heading)
+		for _, task := range items {
+			fmt.Fprintf(&out, "- %s\n", task.Title)
+		}
+		if len(items) == 0 {
+			out.WriteString("- None\n")
+		}
+		out.WriteString("\n")
+	}
+
+	writeSection("Yesterday", completedTasks(tasks, ops, dayStart(yesterday), dayStart(today)))
+
+	var planned []Task
+	if plan.isFor(now) {
+		for _, id := range plan.IDs {
+			if task := findTask(tasks, id); task != nil {
+				planned = append(planned, *task)
+			}
+		}
+	} else {
+		for _, task := range tasks {
+			if !task.Done && isDueToday(task, now) {
+				planned = append(planned, task)
+			}
+		}
+	}
+	writeSection("Today", planned)
+
+	var blockers []Task
+	for _, task := range tasks {
+		for _, tag := range blockedTags {
+			if !task.Done && hasTag(task, tag) {
+				blockers = append(blockers, task)
+				break
+			}
+		}
+	}
+	writeSection("Blockers", blockers)
+
+	var overdue []Task
+	for _, task := range tasks {
+		if isOverdue(task, now) && findTask(planned, task.ID) == nil {
+			task.Title += " (overdue since " + task.Deadline.Format("2006-01-02") + ")"
+			overdue = append(overdue, task)
+		}
+	}
+	writeSection("Overdue", overdue)
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// statsWeeks is how many recent weeks "stats" breaks completions down by
+const statsWeeks = 4
+
+// averageInProgress returns the average time finished tasks spent in
+// progress, from their first session to the end of their last, and how
+// many tasks it covers
+func averageInProgress(tasks []Task) (time.Duration, int) {
+	var total time.Duration
+	count := 0
+	for _, task := range tasks {
+		if !task.Done || len(task.Sessions) == 0 || task.tracking() {
+			continue
+		}
+		total += task.Sessions[len(task.Sessions)-1].End.Sub(task.Sessions[0].Start)
+		count++
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return total / time.Duration(count), count
+}
+
+// averageTimeToDone returns the average time from creation to completion
+// of finished tasks, and how many tasks carry both timestamps
+func averageTimeToDone(tasks []Task) (time.Duration, int) {
+	var total time.Duration
+	count := 0
+	for _, task := range tasks {
+		if !task.Done || task.CreatedAt.IsZero() || task.CompletedAt.IsZero() {
+			continue
+		}
+		total += task.CompletedAt.Sub(task.CreatedAt)
+		count++
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return total / time.Duration(count), count
+}
+
+// completedPerWeek counts the tasks completed in each of the last weeks,
+// oldest first, ending with the current week
+func completedPerWeek(tasks []Task, weeks int, now time.Time) ([]time.Time, []int) {
+	starts := make([]time.Time, weeks)
+	counts := make([]int, weeks)
+	current := weekStart(logicalDate(now))
+	for i := range starts {
+		starts[i] = current.AddDate(0, 0, -7*(weeks-1-i))
+	}
+	for _, task := range tasks {
+		if !task.Done || task.CompletedAt.IsZero() {
+			continue
+		}
+		day := logicalDate(task.CompletedAt)
+		for i := weeks - 1; i >= 0; i-- {
+			if !day.Before(starts[i]) {
+				if day.Before(starts[i].AddDate(0, 0, 7)) {
+					counts[i]++
+				}
+				break
+			}
+		}
+	}
+	return starts, counts
+}
+
+// printStats shows completion figures over the active and archived tasks
+// and how work flows through the list
+func printStats(tasks, archived []Task, now time.Time) {
+	all := append(append([]Task(nil), tasks...), archived...)
+	completed, overdue, untimed := 0, 0, 0
+	for _, task := range all {
+		if task.Done {
+			completed++
+		}
+		if isOverdue(task, now) {
+			overdue++
+		}
+		if task.CreatedAt.IsZero() {
+			untimed++
+		}
+	}
+	fmt.Printf("Created:              %d\n", len(all))
+	fmt.Printf("Completed:            %d\n", completed)
+	if len(all) > 0 {
+		fmt.Printf("Completion rate:      %d%%\n", completed*100/len(all))
+	}
+	fmt.Printf("Overdue:              %d\n", overdue)
+	if average, count := averageTimeToDone(all); count > 0 {
+		fmt.Printf("Average time to done: %s (%d tasks)\n", formatAge(average), count)
+	}
+	starts, counts := completedPerWeek(all, statsWeeks, now)
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	fmt.Printf("Completed per week:   %.1f over the last %d weeks\n", float64(total)/statsWeeks, statsWeeks)
+	for i, start := range starts {
+		fmt.Printf("  week of %s: %d\n", start.Format("2006-01-02"), counts[i])
+	}
+	if untimed > 0 {
+		fmt.Printf("%s%d tasks predate creation and completion times and are left out of the timings%s\n", yellow, untimed, reset)
+	}
+
+	fmt.Println()
+	inProgress := len(trackedTasks(tasks))
+	limit := ""
+	if config.WIPLimit > 0 {
+		limit = fmt.Sprintf(" of %d allowed", config.WIPLimit)
+	}
+	fmt.Printf("In progress:          %d%s\n", inProgress, limit)
+	if average, count := averageInProgress(all); count > 0 {
+		fmt.Printf("Average in progress:  %s (%d finished tasks)\n", formatAge(average), count)
+	} else {
+		fmt.Println("Average in progress:  no finished tasks were tracked")
+	}
+}
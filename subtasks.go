@@ -0,0 +1,35 @@
+package main
+
+import "fmt"
+
+// addSubtask attaches a new task to a parent. A finished parent is reopened
+// since it has open work again.
+func addSubtask(tasks []Task, id, parentID int) ([]Task, error) {
+	parent := findTask(tasks, parentID)
+	if parent == nil {
+		return tasks, fmt.Errorf("parent task #%d not found", parentID)
+	}
+	findTask(tasks, id).ParentID = parentID
+	for parent != nil && parent.Done {
+		parent.setDone(false)
+		parent = findTask(tasks, parent.ParentID)
+	}
+	return tasks, nil
+}
+
+// completeParents marks the ancestors of a finished task done once all
+// their subtasks are done, and returns their IDs
+func completeParents(tasks []Task, id int) []int {
+	var completed []int
+	task := findTask(tasks, id)
+	for task != nil && task.Done {
+		parent := findTask(tasks, task.ParentID)
+		if parent == nil || parent.Done || hasPendingSubtasks(tasks, parent.ID) {
+			break
+		}
+		parent.setDone(true)
+		completed = append(completed, parent.ID)
+		task = parent
+	}
+	return completed
+}
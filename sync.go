@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// completeRemote completes a task that was finished in another app, the
+// way "done" would, and reports whether the task is now done. A task with
+// open subtasks stays open with a warning.
+func completeRemote(tasks []Task, id int) ([]Task, bool) {
+	tasks, _, err := completeTask(tasks, id, time.Now())
+	if err != nil {
+		fmt.Printf("%sWarning: %v%s\n", yellow, err, reset)
+	}
+	task := findTask(tasks, id)
+	return tasks, task != nil && task.Done
+}
+
+// Remote links a task to an item in an external system
+type Remote struct {
+	Source string `json:"source"`
+	ID     string `json:"id"`
+	URL    string `json:"url,omitempty"`
+}
+
+// same reports whether two references point at the same remote item
+func (r Remote) same(other Remote) bool {
+	return r.Source == other.Source && r.ID == other.ID
+}
+
+// remoteItem is an item fetched from an external system
+type remoteItem struct {
+	Remote   Remote
+	Title    string
+	Deadline time.Time
+}
+
+// findRemote returns the task linked to the given remote item, or nil
+func findRemote(tasks []Task, remote Remote) *Task {
+	for i := range tasks {
+		for _, r := range tasks[i].Remotes {
+			if r.same(remote) {
+				return &tasks[i]
+			}
+		}
+	}
+	return nil
+}
+
+// referencePrefix matches the issue key providers put before titles,
+// e.g. "PRJ-12: " or "group/app#3: "
+var referencePrefix = regexp.MustCompile(`^[^\s:]*\d[^\s:]*:\s+`)
+
+// normalizeTitle reduces a title to lowercase words without any leading
+// issue reference so the same item from different sources compares equal
+func normalizeTitle(title string) string {
+	title = referencePrefix.ReplaceAllString(strings.ToLower(title), "")
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// findDuplicate returns an unfinished task synced from another source that
+// has the same title as item, or nil
+func findDuplicate(tasks []Task, item remoteItem) *Task {
+	title := normalizeTitle(item.Title)
+	for i := range tasks {
+		if tasks[i].Done || len(tasks[i].Remotes) == 0 || normalizeTitle(tasks[i].Title) != title {
+			continue
+		}
+		fromSameSource := false
+		for _, r := range tasks[i].Remotes {
+			fromSameSource = fromSameSource || r.Source == item.Remote.Source
+		}
+		if !fromSameSource {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+// applyRemoteItems adds unseen items as new tasks and refreshes the title
+// and deadline of unfinished tasks already linked to them. An item that
+// duplicates a task synced from another source is linked to that task
+// instead, so completing it updates every source.
+func applyRemoteItems(tasks []Task, items []remoteItem) ([]Task, int, int) {
+	var added, updated int
+	for _, item := range items {
+		task := findRemote(tasks, item.Remote)
+		if task == nil {
+			if dup := findDuplicate(tasks, item); dup != nil {
+				dup.Remotes = append(dup.Remotes, item.Remote)
+				updated++
+				continue
+			}
+			var id int
+			tasks, id = addTask(tasks, item.Title, "")
+			task = findTask(tasks, id)
+			task.Deadline = item.Deadline
+			task.Remotes = []Remote{item.Remote}
+			added++
+			continue
+		}
+		for i := range task.Remotes {
+			if task.Remotes[i].same(item.Remote) && item.Remote.URL != "" {
+				task.Remotes[i].URL = item.Remote.URL
+			}
+		}
+		if task.Done {
+			continue
+		}
+		changed := false
+		// Linked sources word titles differently, so only a real rename
+		// counts; likewise only the sole source may clear a deadline
+		if task.Title != item.Title && (len(task.Remotes) == 1 || normalizeTitle(task.Title) != normalizeTitle(item.Title)) {
+			task.Title = item.Title
+			changed = true
+		}
+		if !task.Deadline.Equal(item.Deadline) && (!item.Deadline.IsZero() || len(task.Remotes) == 1) {
+			task.Deadline = item.Deadline
+			changed = true
+		}
+		if changed {
+			updated++
+		}
+	}
+	return tasks, added, updated
+}
+
+// syncCommand runs "sync <provider> [flags]"
+func syncCommand(tasks []Task, args []string) ([]Task, error) {
+	if len(args) == 0 {
+		return tasks, fmt.Errorf("provider is required")
+	}
+	switch args[0] {
+	case "jira":
+		_, flags, err := parseFlags(args[1:], "jql", "instance")
+		if err != nil {
+			return tasks, err
+		}
+		name, cfg, err := jiraInstance(flags.get("instance"))
+		if err != nil {
+			return tasks, err
+		}
+		jql := flags.get("jql")
+		if jql == "" {
+			jql = cfg.JQL
+		}
+		if jql == "" {
+			return tasks, fmt.Errorf("--jql is required")
+		}
+		return syncJira(tasks, name, cfg, jql)
+	case "gitlab":
+		return syncGitLab(tasks, config.GitLab)
+	case "mstodo":
+		return syncMicrosoft(tasks, config.Microsoft)
+	case "vault":
+		return syncVault(tasks, config.Vault)
+	case "caldav":
+		_, flags, err := parseFlags(args[1:], "url", "user")
+		if err != nil {
+			return tasks, err
+		}
+		cfg := config.CalDAV
+		if flags.has("url") {
+			cfg.URL = flags.get("url")
+		}
+		if flags.has("user") {
+			cfg.User = flags.get("user")
+		}
+		return syncCalDAV(tasks, cfg)
+	case "gcal":
+		return syncGoogleCalendar(tasks, config.Google)
+	case "all":
+		return syncAll(tasks)
+	default:
+		if findPlugin(args[0]) != "" {
+			return syncPlugin(tasks, args[0], args[1:])
+		}
+		return tasks, fmt.Errorf("unknown provider %q (no %s%s on PATH)", args[0], pluginPrefix, args[0])
+	}
+}
+
+// syncAll runs every configured provider in turn, so a task linked to
+// several sources is brought up to date in all of them
+func syncAll(tasks []Task) ([]Task, error) {
+	var err error
+	ran := 0
+	for name, cfg := range config.Jira {
+		if cfg.JQL == "" {
+			continue
+		}
+		if tasks, err = syncJira(tasks, name, cfg, cfg.JQL); err != nil {
+			return tasks, err
+		}
+		ran++
+	}
+	if config.GitLab != (GitLabConfig{}) {
+		if tasks, err = syncGitLab(tasks, config.GitLab); err != nil {
+			return tasks, err
+		}
+		ran++
+	}
+	if config.Microsoft.ClientID != "" {
+		if tasks, err = syncMicrosoft(tasks, config.Microsoft); err != nil {
+			return tasks, err
+		}
+		ran++
+	}
+	if config.Vault.Path != "" {
+		if tasks, err = syncVault(tasks, config.Vault); err != nil {
+			return tasks, err
+		}
+		ran++
+	}
+	if config.CalDAV.URL != "" {
+		if tasks, err = syncCalDAV(tasks, config.CalDAV); err != nil {
+			return tasks, err
+		}
+		ran++
+	}
+	if config.Google.ClientID != "" {
+		if tasks, err = syncGoogleCalendar(tasks, config.Google); err != nil {
+			return tasks, err
+		}
+		ran++
+	}
+	for name := range config.Plugins {
+		if tasks, err = syncPlugin(tasks, name, nil); err != nil {
+			return tasks, err
+		}
+		ran++
+	}
+	if ran == 0 {
+		return tasks, fmt.Errorf("no providers configured")
+	}
+	return tasks, nil
+}
+
+// jiraInstance picks the configured Jira instance by name, or the only one
+// when no name is given
+func jiraInstance(name string) (string, JiraConfig, error) {
+	if name == "" && len(config.Jira) == 1 {
+		for only, cfg := range config.Jira {
+			return only, cfg, nil
+		}
+	}
+	if name == "" {
+		return "", JiraConfig{}, fmt.Errorf("--instance is required when %d Jira instances are configured", len(config.Jira))
+	}
+	cfg, ok := config.Jira[name]
+	if !ok {
+		return "", JiraConfig{}, fmt.Errorf("no Jira instance %q in config", name)
+	}
+	return name, cfg, nil
+}
+
+// httpClient is shared by all sync providers
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// doJSON sends a request with an optional JSON body and decodes a JSON
+// response into out when it is not nil
+func doJSON(req *http.Request, body interface{}, out interface{}) error {
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.ContentLength = int64(len(data))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// replay runs sync with --replay-http on a capture in testdata, so the
+// provider sees the recorded server. The test fails if the provider did
+// not send every recorded request.
+func replay(t *testing.T, tasks []Task, args ...string) []Task {
+	t.Helper()
+	saved := httpClient.Transport
+	defer func() { httpClient.Transport = saved }()
+
+	args, finish, err := setupHTTPDebug(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err = syncCommand(tasks, args)
+	if err != nil {
+		t.Fatalf("sync %v: %v", args, err)
+	}
+	if err := finish(); err != nil {
+		t.Fatal(err)
+	}
+	transport := httpClient.Transport.(*replayTransport)
+	for i, used := range transport.used {
+		if !used {
+			ex := transport.exchanges[i]
+			t.Errorf("sync %v never sent %s %s", args, ex.Method, ex.URL)
+		}
+	}
+	return tasks
+}
+
+// withConfig replaces the active configuration and data directory for
+// the rest of a test
+func withConfig(t *testing.T, cfg Config) {
+	t.Setenv("TODO_DIR", t.TempDir())
+	saved := config
+	config = cfg
+	t.Cleanup(func() { config = saved })
+}
+
+func TestReplayUnknownRequest(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "test-token")
+	withConfig(t, Config{GitLab: GitLabConfig{URL: "https://other.example.com"}})
+	capture := filepath.Join(t.TempDir(), "capture.jsonl")
+	recorded := `{"method":"GET","url":"https://gitlab.example.com/api/v4/issues","status":200,"response_body":"[]"}` + "\n"
+	if err := os.WriteFile(capture, []byte(recorded), 0644); err != nil {
+		t.Fatal(err)
+	}
+	saved := httpClient.Transport
+	defer func() { httpClient.Transport = saved }()
+	args, _, err := setupHTTPDebug([]string{"gitlab", "--replay-http", capture})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := syncCommand(nil, args); err == nil {
+		t.Error("sync against a server missing from the capture succeeded")
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct{ title, want string }{
+		{"PRJ-12: Fix the login page", "fix the login page"},
+		{"group/app#3: Fix the login page!", "fix the login page"},
+		{"Fix   the LOGIN page", "fix the login page"},
+		{"Review group/app!9: Add caching", "review group app 9 add caching"},
+		{"Call: mom", "call mom"},
+	}
+	for _, tt := range tests {
+		if got := normalizeTitle(tt.title); got != tt.want {
+			t.Errorf("normalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// hasTag reports whether a task carries the tag, ignoring case
+func hasTag(task Task, tag string) bool {
+	for _, t := range task.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// addTags returns tags with the new ones appended, skipping blanks and
+// tags already present
+func addTags(tags []string, more []string) []string {
+	for _, tag := range more {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "+")
+		if tag != "" && !hasTag(Task{Tags: tags}, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// tagLabels renders tags for list output, e.g. " +work +urgent"
+func tagLabels(tags []string) string {
+	label := ""
+	for _, tag := range tags {
+		label += " +" + tag
+	}
+	return label
+}
+
+// removeTag returns tags without tag, ignoring case
+func removeTag(tags []string, tag string) []string {
+	var kept []string
+	for _, t := range tags {
+		if !strings.EqualFold(t, tag) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// tagTasks adds or removes a tag on every task matching the filter and
+// returns how many tasks changed
+func tagTasks(tasks []Task, action, tag, expr string, now time.Time) ([]Task, int, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "+")
+	if tag == "" {
+		return tasks, 0, fmt.Errorf("tag name is required")
+	}
+	filter, err := parseFilter(expr, now)
+	if err != nil {
+		return tasks, 0, err
+	}
+	changed := 0
+	for i := range tasks {
+		if !filter.match(tasks[i]) || hasTag(tasks[i], tag) == (action == "add") {
+			continue
+		}
+		if action == "add" {
+			tasks[i].Tags = addTags(tasks[i].Tags, []string{tag})
+		} else {
+			tasks[i].Tags = removeTag(tasks[i].Tags, tag)
+		}
+		changed++
+	}
+	return tasks, changed, nil
+}
+
+// retagTasks renames a tag on every task. Tasks that already carry the new
+// name just lose the old one, so retagging also merges synonyms.
+func retagTasks(tasks []Task, from, to string) ([]Task, int, error) {
+	from = strings.TrimPrefix(strings.TrimSpace(from), "+")
+	to = strings.TrimPrefix(strings.TrimSpace(to), "+")
+	if from == "" || to == "" {
+		return tasks, 0, fmt.Errorf("old and new tag names are required")
+	}
+	changed := 0
+	for i := range tasks {
+		if !hasTag(tasks[i], from) {
+			continue
+		}
+		var renamed []string
+		for _, t := range tasks[i].Tags {
+			if strings.EqualFold(t, from) {
+				t = to
+			}
+			renamed = addTags(renamed, []string{t})
+		}
+		tasks[i].Tags = renamed
+		changed++
+	}
+	return tasks, changed, nil
+}
+
+// tagCount is how many open and done tasks carry a tag
+type tagCount struct {
+	Name string
+	Open int
+	Done int
+}
+
+// countTags tallies every tag in use, most open tasks first. Tags that
+// differ only in case are counted together under the first spelling seen.
+func countTags(tasks []Task) []tagCount {
+	var counts []tagCount
+	index := map[string]int{}
+	for _, task := range tasks {
+		for _, tag := range task.Tags {
+			key := strings.ToLower(tag)
+			i, seen := index[key]
+			if !seen {
+				i = len(counts)
+				index[key] = i
+				counts = append(counts, tagCount{Name: tag})
+			}
+			if task.Done {
+				counts[i].Done++
+			} else {
+				counts[i].Open++
+			}
+		}
+	}
+	sort.SliceStable(counts, func(i, j int) bool {
+		if counts[i].Open != counts[j].Open {
+			return counts[i].Open > counts[j].Open
+		}
+		return strings.ToLower(counts[i].Name) < strings.ToLower(counts[j].Name)
+	})
+	return counts
+}
+
+// printTags lists tags with their open and done task counts
+func printTags(counts []tagCount) {
+	if len(counts) == 0 {
+		fmt.Println(yellow + "No tags in use" + reset)
+		return
+	}
+	for _, count := range counts {
+		fmt.Printf("+%s  %d open, %d done\n", count.Name, count.Open, count.Done)
+	}
+}
+
+// pruneTags removes the tags no open task uses any more from the done
+// tasks still carrying them, and returns their names
+func pruneTags(tasks []Task) ([]Task, []string) {
+	var pruned []string
+	for _, count := range countTags(tasks) {
+		if count.Open > 0 {
+			continue
+		}
+		for i := range tasks {
+			tasks[i].Tags = removeTag(tasks[i].Tags, count.Name)
+		}
+		pruned = append(pruned, count.Name)
+	}
+	return tasks, pruned
+}
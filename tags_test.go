@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// tagsOf returns each task's tags by ID
+func tagsOf(tasks []Task) map[int][]string {
+	tags := map[int][]string{}
+	for _, task := range tasks {
+		tags[task.ID] = task.Tags
+	}
+	return tags
+}
+
+func TestTagTasks(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	tasks := []Task{
+		{ID: 1, Title: "a", Project: "work", Tags: []string{"Urgent"}},
+		{ID: 2, Title: "b", Project: "work"},
+		{ID: 3, Title: "c", Project: "home"},
+		{ID: 4, Title: "d", Project: "work", Done: true},
+	}
+	tasks, changed, err := tagTasks(tasks, "add", "+urgent", "project:work pending", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// #1 already had the tag in another case
+	if changed != 1 {
+		t.Errorf("add changed %d tasks, want 1", changed)
+	}
+	want := map[int][]string{1: {"Urgent"}, 2: {"urgent"}, 3: nil, 4: nil}
+	if got := tagsOf(tasks); !reflect.DeepEqual(got, want) {
+		t.Errorf("after add: %v, want %v", got, want)
+	}
+
+	tasks, changed, err = tagTasks(tasks, "remove", "URGENT", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("remove changed %d tasks, want 2", changed)
+	}
+	for id, tags := range tagsOf(tasks) {
+		if len(tags) != 0 {
+			t.Errorf("#%d still has %v", id, tags)
+		}
+	}
+
+	if _, _, err := tagTasks(tasks, "add", "+", "", now); err == nil {
+		t.Error("adding an empty tag succeeded")
+	}
+	if _, _, err := tagTasks(tasks, "add", "x", "colour:red", now); err == nil {
+		t.Error("an unknown filter term was accepted")
+	}
+}
+
+func TestRetagTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Tags: []string{"wrk", "urgent"}},
+		{ID: 2, Tags: []string{"Work", "WRK"}},
+		{ID: 3, Tags: []string{"home"}},
+	}
+	tasks, changed, err := retagTasks(tasks, "+wrk", "Work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("retag changed %d tasks, want 2", changed)
+	}
+	want := map[int][]string{1: {"Work", "urgent"}, 2: {"Work"}, 3: {"home"}}
+	if got := tagsOf(tasks); !reflect.DeepEqual(got, want) {
+		t.Errorf("after retag: %v, want %v", got, want)
+	}
+	if _, _, err := retagTasks(tasks, "wrk", " "); err == nil {
+		t.Error("retag to an empty name succeeded")
+	}
+}
+
+func TestCountAndPruneTags(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Tags: []string{"work", "q1"}},
+		{ID: 2, Tags: []string{"Work"}},
+		{ID: 3, Tags: []string{"home"}},
+		{ID: 4, Done: true, Tags: []string{"q1", "old", "Home"}},
+		{ID: 5, Done: true, Tags: []string{"OLD", "trip"}},
+	}
+	want := []tagCount{
+		{Name: "work", Open: 2},
+		{Name: "home", Open: 1, Done: 1},
+		{Name: "q1", Open: 1, Done: 1},
+		{Name: "old", Done: 2},
+		{Name: "trip", Done: 1},
+	}
+	if got := countTags(tasks); !reflect.DeepEqual(got, want) {
+		t.Errorf("countTags = %+v, want %+v", got, want)
+	}
+
+	tasks, pruned := pruneTags(tasks)
+	if !reflect.DeepEqual(pruned, []string{"old", "trip"}) {
+		t.Errorf("pruned %v, want [old trip]", pruned)
+	}
+	if got := tagsOf(tasks); !reflect.DeepEqual(got[4], []string{"q1", "Home"}) || got[5] != nil {
+		t.Errorf("done tasks after prune: %v and %v", got[4], got[5])
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Targets remembers which task was picked for an ambiguous title so the
+// same invocation doesn't ask again within one shell session
+type Targets struct {
+	Session int            `json:"session"`
+	Choices map[string]int `json:"choices"`
+}
+
+// loadTargets reads the current session's choices from targets.json file
+func loadTargets() Targets {
+	targets := Targets{Session: os.Getppid(), Choices: map[string]int{}}
+	file, err := os.ReadFile(dataPath("targets.json"))
+	if err != nil {
+		return targets
+	}
+	var saved Targets
+	if json.Unmarshal(file, &saved) == nil && saved.Session == targets.Session && saved.Choices != nil {
+		targets.Choices = saved.Choices
+	}
+	return targets
+}
+
+// saveTargets writes the session's choices to targets.json file
+func saveTargets(targets Targets) error {
+	data, err := json.MarshalIndent(targets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dataPath("targets.json"), data, 0644)
+}
+
+// matchTitle returns the tasks whose title contains every word of query.
+// Exact title matches win over partial ones, and among those unfinished
+// tasks win over done ones. Every remaining match is returned so that the
+// caller can ask which one was meant.
+func matchTitle(tasks []Task, query string) []Task {
+	query = normalizeTitle(query)
+	var exact, open, matches []Task
+	for _, task := range tasks {
+		title := normalizeTitle(task.Title)
+		if title == query {
+			exact = append(exact, task)
+			if !task.Done {
+				open = append(open, task)
+			}
+			continue
+		}
+		all := query != ""
+		for _, word := range strings.Fields(query) {
+			if !strings.Contains(title, word) {
+				all = false
+				break
+			}
+		}
+		if all {
+			matches = append(matches, task)
+		}
+	}
+	switch {
+	case len(open) > 0:
+		return open
+	case len(exact) > 0:
+		return exact
+	}
+	return matches
+}
+
+// resolveTarget turns a command argument into a task ID. Numbers are IDs;
+// anything else is matched against titles, asking which task was meant
+// when several match.
+func resolveTarget(tasks []Task, arg string, in *bufio.Reader) (int, error) {
+	if id, err := strconv.Atoi(arg); err == nil {
+		return id, nil
+	}
+	matches := matchTitle(tasks, arg)
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("no task matches %q", arg)
+	case 1:
+		return matches[0].ID, nil
+	}
+
+	targets := loadTargets()
+	key := normalizeTitle(arg)
+	if id, ok := targets.Choices[key]; ok && findTask(matches, id) != nil {
+		return id, nil
+	}
+
+	fmt.Printf("%q matches %d tasks:\n", arg, len(matches))
+	for i, task := range matches {
+		var context []string
+		if task.Done {
+			context = append(context, "done")
+		}
+		if !task.Deadline.IsZero() {
+			context = append(context, "due "+task.Deadline.Format("2006-01-02"))
+		}
+		if task.Project != "" {
+			context = append(context, "project "+task.Project)
+		}
+		if len(task.Tags) > 0 {
+			context = append(context, strings.TrimSpace(tagLabels(task.Tags)))
+		}
+		extra := ""
+		if len(context) > 0 {
+			extra = " (" + strings.Join(context, ", ") + ")"
+		}
+		fmt.Printf("  %d) #%d: %s%s\n", i+1, task.ID, task.Title, extra)
+	}
+	fmt.Printf("Which one? [1-%d] ", len(matches))
+	answer, _ := in.ReadString('\n')
+	n, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || n < 1 || n > len(matches) {
+		return 0, fmt.Errorf("no task chosen for %q", arg)
+	}
+
+	id := matches[n-1].ID
+	targets.Choices[key] = id
+	if err := saveTargets(targets); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
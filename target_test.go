@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchTitle(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Title: "Pay rent", Done: true},
+		{ID: 2, Title: "pay rent"},
+		{ID: 3, Title: "Pay rent for the garage"},
+		{ID: 4, Title: "Call the landlord about rent"},
+		{ID: 5, Title: "Buy milk", Done: true},
+		{ID: 6, Title: "buy milk!"},
+		{ID: 7, Title: "Water plants"},
+		{ID: 8, Title: "Water plants", Done: true},
+		{ID: 9, Title: "PRJ-12: Fix login"},
+		{ID: 10, Title: "Fix login"},
+	}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		// Exact matches win, open ones over done ones
+		{"pay rent", []int{2}},
+		{"PAY RENT", []int{2}},
+		{"buy milk", []int{6}},
+		// Every open exact match is offered
+		{"fix login", []int{9, 10}},
+		// Otherwise every task containing all words
+		{"rent", []int{1, 2, 3, 4}},
+		{"rent garage", []int{3}},
+		{"landlord rent", []int{4}},
+		{"plants water", []int{7, 8}},
+		{"dentist", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		var got []int
+		for _, task := range matchTitle(tasks, tt.query) {
+			got = append(got, task.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("matchTitle(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTitleOnlyDone(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Title: "Renew passport", Done: true},
+		{ID: 2, Title: "renew passport", Done: true},
+		{ID: 3, Title: "Renew passport photos"},
+	}
+	var got []int
+	for _, task := range matchTitle(tasks, "renew passport") {
+		got = append(got, task.ID)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("matchTitle with only done exact matches = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// timesheetRow is the work under one tag on one day
+type timesheetRow struct {
+	Date      time.Time
+	Tag       string
+	Spent     time.Duration
+	Completed int
+	Titles    []string
+}
+
+// timesheet breaks the tracked time and completions between the dates
+// from and to, both included, down by day and tag. Sessions are split
+// where a day ends, a task with several tags counts under each, and
+// untagged work has an empty tag. Rows are ordered by date, then tag.
+// The total counts each task once, however many tags it has.
+func timesheet(tasks []Task, from, to, now time.Time) ([]timesheetRow, timesheetRow) {
+	start, end := dayStart(from), dayStart(to.AddDate(0, 0, 1))
+	var total timesheetRow
+	rows := map[string]*timesheetRow{}
+	row := func(date time.Time, tag string, title string) *timesheetRow {
+		key := date.Format("2006-01-02") + "\x00" + strings.ToLower(tag)
+		r := rows[key]
+		if r == nil {
+			r = &timesheetRow{Date: date, Tag: tag}
+			rows[key] = r
+		}
+		for _, seen := range r.Titles {
+			if seen == title {
+				return r
+			}
+		}
+		r.Titles = append(r.Titles, title)
+		return r
+	}
+
+	for _, task := range tasks {
+		tags := task.Tags
+		if len(tags) == 0 {
+			tags = []string{""}
+		}
+		for _, session := range task.Sessions {
+			at, until := session.Start, session.End
+			if until.IsZero() {
+				until = now
+			}
+			if at.Before(start) {
+				at = start
+			}
+			if until.After(end) {
+				until = end
+			}
+			for at.Before(until) {
+				date := logicalDate(at)
+				next := dayStart(date.AddDate(0, 0, 1))
+				if next.After(until) {
+					next = until
+				}
+				for _, tag := range tags {
+					row(date, tag, task.Title).Spent += next.Sub(at)
+				}
+				total.Spent += next.Sub(at)
+				at = next
+			}
+		}
+		if task.Done && !task.CompletedAt.Before(start) && task.CompletedAt.Before(end) {
+			for _, tag := range tags {
+				row(logicalDate(task.CompletedAt), tag, task.Title).Completed++
+			}
+			total.Completed++
+		}
+	}
+
+	sheet := make([]timesheetRow, 0, len(rows))
+	for _, r := range rows {
+		sheet = append(sheet, *r)
+	}
+	sort.Slice(sheet, func(i, j int) bool {
+		a, b := sheet[i], sheet[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		// Untagged work comes last on each day
+		if (a.Tag == "") != (b.Tag == "") {
+			return b.Tag == ""
+		}
+		return strings.ToLower(a.Tag) < strings.ToLower(b.Tag)
+	})
+	return sheet, total
+}
+
+// timesheetCSV renders a timesheet for spreadsheets and invoicing tools,
+// with the time in decimal hours
+func timesheetCSV(sheet []timesheetRow) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"date", "tag", "hours", "completed", "tasks"})
+	for _, r := range sheet {
+		w.Write([]string{
+			r.Date.Format("2006-01-02"),
+			r.Tag,
+			fmt.Sprintf("%.2f", r.Spent.Hours()),
+			fmt.Sprint(r.Completed),
+			strings.Join(r.Titles, "; "),
+		})
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+// timesheetText renders a timesheet as a table with a total line
+func timesheetText(sheet []timesheetRow, total timesheetRow, from, to time.Time) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "Timesheet %s to %s\n\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if len(sheet) == 0 {
+		out.WriteString("No tracked time or completed tasks\n")
+		return out.String()
+	}
+	fmt.Fprintf(&out, "%-10s  %-16s  %7s  %4s\n", "Date", "Tag", "Time", "Done")
+	for _, r := range sheet {
+		tag := "No tag"
+		if r.Tag != "" {
+			tag = "+" + r.Tag
+		}
+		fmt.Fprintf(&out, "%-10s  %-16s  %7s  %4d\n", r.Date.Format("2006-01-02"), tag, formatSpent(r.Spent), r.Completed)
+	}
+	fmt.Fprintf(&out, "%-10s  %-16s  %7s  %4d\n", "Total", "", formatSpent(total.Spent), total.Completed)
+	return out.String()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimesheet(t *testing.T) {
+	first := day(t, "2025-05-01")
+	at := func(days, hours int) time.Time {
+		return first.AddDate(0, 0, days).Add(time.Duration(hours) * time.Hour)
+	}
+	tasks := []Task{
+		// Runs past midnight and counts under both tags
+		{ID: 1, Title: "Client API", Tags: []string{"acme", "api"}, Done: true, CompletedAt: at(1, 10),
+			Sessions: []Session{{Start: at(0, 22), End: at(1, 1)}}},
+		{ID: 2, Title: "Invoice", Sessions: []Session{{Start: at(2, 9), End: at(2, 11)}, {Start: at(5, 9), End: at(5, 10)}}},
+		// Still running, counted up to now
+		{ID: 3, Title: "Review", Tags: []string{"acme"}, Sessions: []Session{{Start: at(2, 14)}}},
+		{ID: 4, Title: "Before", Done: true, CompletedAt: at(-1, 10)},
+	}
+	sheet, total := timesheet(tasks, first, day(t, "2025-05-03"), at(2, 15))
+
+	type row struct {
+		date, tag string
+		spent     time.Duration
+		completed int
+	}
+	var got []row
+	for _, r := range sheet {
+		got = append(got, row{r.Date.Format("2006-01-02"), r.Tag, r.Spent, r.Completed})
+	}
+	want := []row{
+		{"2025-05-01", "acme", 2 * time.Hour, 0},
+		{"2025-05-01", "api", 2 * time.Hour, 0},
+		{"2025-05-02", "acme", time.Hour, 1},
+		{"2025-05-02", "api", time.Hour, 1},
+		{"2025-05-03", "acme", time.Hour, 0},
+		{"2025-05-03", "", 2 * time.Hour, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("timesheet = %v, want %v", got, want)
+	}
+	if total.Spent != 6*time.Hour || total.Completed != 1 {
+		t.Errorf("total %v and %d done, want 6h and 1 done", total.Spent, total.Completed)
+	}
+
+	csv := string(timesheetCSV(sheet[:1]))
+	if want := "date,tag,hours,completed,tasks\n2025-05-01,acme,2.00,0,Client API\n"; csv != want {
+		t.Errorf("timesheetCSV = %q, want %q", csv, want)
+	}
+}
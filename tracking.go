@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Session is a stretch of work on a task. A running session has no End.
+type Session struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end,omitempty"`
+}
+
+// tracking reports whether a task has a running session
+func (t Task) tracking() bool {
+	return len(t.Sessions) > 0 && t.Sessions[len(t.Sessions)-1].End.IsZero()
+}
+
+// timeSpent adds up a task's sessions, counting a running one up to now
+func (t Task) timeSpent(now time.Time) time.Duration {
+	var total time.Duration
+	for _, session := range t.Sessions {
+		end := session.End
+		if end.IsZero() {
+			end = now
+		}
+		total += end.Sub(session.Start)
+	}
+	return total
+}
+
+// formatSpent renders tracked time as hours and minutes, e.g. "2h05m"
+func formatSpent(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
+
+// spentLabel is the tracked time shown in task lists
+func spentLabel(task Task, now time.Time) string {
+	if task.tracking() {
+		return " [" + yellow + "tracking " + formatSpent(task.timeSpent(now)) + reset + "]"
+	}
+	if len(task.Sessions) > 0 {
+		return " [" + formatSpent(task.timeSpent(now)) + "]"
+	}
+	return ""
+}
+
+// startTracking opens a work session on a task
+func startTracking(tasks []Task, id int, now time.Time) ([]Task, error) {
+	task := findTask(tasks, id)
+	switch {
+	case task == nil:
+		return tasks, fmt.Errorf("task #%d not found", id)
+	case task.Done:
+		return tasks, fmt.Errorf("task #%d is done", id)
+	case task.tracking():
+		return tasks, fmt.Errorf("task #%d is already being tracked", id)
+	}
+	task.Sessions = append(task.Sessions, Session{Start: now})
+	return tasks, nil
+}
+
+// stopTracking closes a task's running session and returns its length
+func stopTracking(tasks []Task, id int, now time.Time) ([]Task, time.Duration, error) {
+	task := findTask(tasks, id)
+	if task == nil {
+		return tasks, 0, fmt.Errorf("task #%d not found", id)
+	}
+	if !task.tracking() {
+		return tasks, 0, fmt.Errorf("task #%d is not being tracked", id)
+	}
+	last := &task.Sessions[len(task.Sessions)-1]
+	last.End = now
+	return tasks, last.End.Sub(last.Start), nil
+}
+
+// trackedTasks returns the IDs of tasks with a running session
+func trackedTasks(tasks []Task) []int {
+	var ids []int
+	for _, task := range tasks {
+		if task.tracking() {
+			ids = append(ids, task.ID)
+		}
+	}
+	return ids
+}
+
+// overWIPLimit reports whether starting one more task would exceed the
+// configured limit on tasks in progress
+func overWIPLimit(tasks []Task) bool {
+	return config.WIPLimit > 0 && len(trackedTasks(tasks))+1 > config.WIPLimit
+}
+
+// inProgressSince returns when a task went into progress: the start of its
+// first session
+func inProgressSince(task Task) time.Time {
+	if len(task.Sessions) == 0 {
+		return time.Time{}
+	}
+	return task.Sessions[0].Start
+}
+
+// printStatus lists the tasks in progress, oldest first, with how long
+// each has been in progress
+func printStatus(tasks []Task, now time.Time) {
+	var active []Task
+	for _, task := range tasks {
+		if task.tracking() {
+			active = append(active, task)
+		}
+	}
+	if len(active) == 0 {
+		fmt.Println(yellow + "No tasks in progress" + reset)
+		return
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		return inProgressSince(active[i]).Before(inProgressSince(active[j]))
+	})
+	limit := ""
+	if config.WIPLimit > 0 {
+		limit = fmt.Sprintf(" (limit %d)", config.WIPLimit)
+	}
+	fmt.Printf("In progress: %d%s\n", len(active), limit)
+	for _, task := range active {
+		fmt.Printf("#%d: %s - in progress for %s, %s tracked\n", task.ID, task.Title,
+			formatAge(now.Sub(inProgressSince(task))), formatSpent(task.timeSpent(now)))
+	}
+	if config.WIPLimit > 0 && len(active) > config.WIPLimit {
+		fmt.Printf("%sOver the WIP limit by %d, finish or stop something before starting more%s\n", red, len(active)-config.WIPLimit, reset)
+	}
+}
+
+// formatAge renders a long duration in days and hours, e.g. "3d 4h"
+func formatAge(d time.Duration) string {
+	if d < 24*time.Hour {
+		return formatSpent(d)
+	}
+	days := int(d / (24 * time.Hour))
+	return fmt.Sprintf("%dd %dh", days, int((d-time.Duration(days)*24*time.Hour)/time.Hour))
+}
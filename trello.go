@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trelloBoard is the part of a Trello board export used for import/export
+type trelloBoard struct {
+	Name  string       `json:"name"`
+	Lists []trelloList `json:"lists"`
+	Cards []trelloCard `json:"cards"`
+}
+
+// trelloList is a column on a board
+type trelloList struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Closed bool   `json:"closed,omitempty"`
+}
+
+// trelloCard is a single card on a board
+type trelloCard struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	ShortURL    string        `json:"shortUrl,omitempty"`
+	IDList      string        `json:"idList"`
+	Closed      bool          `json:"closed,omitempty"`
+	Due         string        `json:"due,omitempty"`
+	DueComplete bool          `json:"dueComplete,omitempty"`
+	Labels      []trelloLabel `json:"labels,omitempty"`
+}
+
+// trelloLabel is a label on a card; it maps to a tag
+type trelloLabel struct {
+	Name string `json:"name"`
+}
+
+// trelloDoneLists are list names treated as finished work
+var trelloDoneLists = []string{"done", "complete", "completed", "finished"}
+
+// importTrello adds the open cards of a board export as tasks. Cards in a
+// done-style list or with a completed due date complete their task the way
+// "done" does, and cards imported before are updated instead of duplicated.
+// Named labels become tags, with spaces turned into dashes.
+func importTrello(tasks []Task, data []byte) ([]Task, int, int, error) {
+	var board trelloBoard
+	if err := json.Unmarshal(data, &board); err != nil {
+		return tasks, 0, 0, fmt.Errorf("not a Trello board export: %v", err)
+	}
+
+	doneList := map[string]bool{}
+	closedList := map[string]bool{}
+	for _, list := range board.Lists {
+		closedList[list.ID] = list.Closed
+		for _, name := range trelloDoneLists {
+			if strings.EqualFold(strings.TrimSpace(list.Name), name) {
+				doneList[list.ID] = true
+			}
+		}
+	}
+
+	var added, updated int
+	for _, card := range board.Cards {
+		if card.Closed || closedList[card.IDList] {
+			continue
+		}
+		remote := Remote{Source: "trello", ID: card.ID, URL: card.ShortURL}
+		task := findRemote(tasks, remote)
+		if task == nil {
+			var id int
+			tasks, id = addTask(tasks, card.Name, "")
+			task = findTask(tasks, id)
+			task.Remotes = []Remote{remote}
+			added++
+		} else {
+			for i := range task.Remotes {
+				if task.Remotes[i].same(remote) {
+					task.Remotes[i].URL = remote.URL
+				}
+			}
+			updated++
+		}
+		task.Title = card.Name
+		for _, label := range card.Labels {
+			if tag := strings.Join(strings.Fields(label.Name), "-"); tag != "" {
+				task.Tags = addTags(task.Tags, []string{tag})
+			}
+		}
+		task.Deadline = time.Time{}
+		if due, err := time.Parse(time.RFC3339, card.Due); err == nil {
+			task.Deadline = deadlineOn(due.Local())
+		}
+		switch done := doneList[card.IDList] || card.DueComplete; {
+		case done && !task.Done:
+			tasks, _ = completeRemote(tasks, task.ID)
+		case !done && task.Done:
+			task.setDone(false)
+		}
+	}
+	return tasks, added, updated, nil
+}
+
+// exportTrello renders tasks as a board with "To Do" and "Done" lists that
+// Trello import tools and importTrello understand, with tags as labels
+func exportTrello(tasks []Task) ([]byte, error) {
+	board := trelloBoard{
+		Name:  "To-Do List",
+		Lists: []trelloList{{ID: "todo", Name: "To Do"}, {ID: "done", Name: "Done"}},
+	}
+
+	for _, task := range tasks {
+		card := trelloCard{ID: "task-" + strconv.Itoa(task.ID), Name: task.Title, IDList: "todo"}
+		for _, remote := range task.Remotes {
+			if remote.Source == "trello" {
+				card.ID = remote.ID
+			}
+		}
+		if task.Done {
+			card.IDList = "done"
+		}
+		for _, tag := range task.Tags {
+			card.Labels = append(card.Labels, trelloLabel{Name: tag})
+		}
+		if !task.Deadline.IsZero() {
+			card.Due = dateOf(task.Deadline).UTC().Format(time.RFC3339)
+			card.DueComplete = task.Done
+		}
+		board.Cards = append(board.Cards, card)
+	}
+	return json.MarshalIndent(board, "", "  ")
+}
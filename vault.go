@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// VaultConfig points "sync vault" at a directory of Markdown notes
+type VaultConfig struct {
+	Path string `json:"path,omitempty"`
+	// Tag limits syncing to checklist items containing it, e.g. "#todo"
+	Tag string `json:"tag,omitempty"`
+}
+
+// vaultItem is a checklist item found in a note
+type vaultItem struct {
+	File string // relative to the vault root
+	Line int    // zero-based
+	Done bool
+	Text string
+}
+
+var (
+	checkboxPattern = regexp.MustCompile(`^(\s*[-*+] \[)([ xX])(\] )(.*)$`)
+	// Deadlines are written the way the Obsidian Tasks plugin does
+	vaultDuePattern = regexp.MustCompile(`📅\s*(\d{4}-\d{2}-\d{2})`)
+)
+
+// scanVault finds every checklist item in the Markdown files under root
+func scanVault(root, tag string) ([]vaultItem, error) {
+	var items []vaultItem
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != root {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".md") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		for i, line := range strings.Split(string(data), "\n") {
+			m := checkboxPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+			if m == nil || (tag != "" && !strings.Contains(m[4], tag)) {
+				continue
+			}
+			items = append(items, vaultItem{File: rel, Line: i, Done: m[2] != " ", Text: strings.TrimSpace(m[4])})
+		}
+		return nil
+	})
+	return items, err
+}
+
+// checkVaultItem ticks off an item in its note, finding it by text in case
+// lines have moved since the scan
+func checkVaultItem(root string, item vaultItem) error {
+	path := filepath.Join(root, item.File)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(data), "\n")
+	tick := func(i int) bool {
+		trimmed := strings.TrimRight(lines[i], "\r")
+		m := checkboxPattern.FindStringSubmatch(trimmed)
+		if m == nil || m[2] != " " || strings.TrimSpace(m[4]) != item.Text {
+			return false
+		}
+		lines[i] = m[1] + "x" + m[3] + m[4] + lines[i][len(trimmed):]
+		return true
+	}
+	found := item.Line < len(lines) && tick(item.Line)
+	for i := 0; !found && i < len(lines); i++ {
+		found = tick(i)
+	}
+	if !found {
+		return fmt.Errorf("%s: item %q not found", item.File, item.Text)
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+}
+
+// fileURL links to a local file
+func fileURL(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
+
+// syncVault mirrors checklist items as tasks. Items ticked in a note
+// complete their task, and tasks completed here are ticked in the note.
+func syncVault(tasks []Task, cfg VaultConfig) ([]Task, error) {
+	if cfg.Path == "" {
+		return tasks, fmt.Errorf("set vault.path in config")
+	}
+	items, err := scanVault(cfg.Path, cfg.Tag)
+	if err != nil {
+		return tasks, err
+	}
+
+	var remoteItems []remoteItem
+	var ticked, completed int
+	for _, item := range items {
+		remote := Remote{Source: "vault", ID: item.File + "#" + item.Text, URL: fileURL(filepath.Join(cfg.Path, item.File))}
+		task := findRemote(tasks, remote)
+		switch {
+		case item.Done:
+			if task != nil && !task.Done {
+				var done bool
+				if tasks, done = completeRemote(tasks, task.ID); done {
+					completed++
+				}
+			}
+		case task != nil && task.Done:
+			if err := checkVaultItem(cfg.Path, item); err != nil {
+				return tasks, err
+			}
+			ticked++
+		default:
+			title := strings.TrimSpace(vaultDuePattern.ReplaceAllString(item.Text, ""))
+			ri := remoteItem{Remote: remote, Title: title}
+			if m := vaultDuePattern.FindStringSubmatch(item.Text); m != nil {
+				ri.Deadline, _ = time.Parse("2006-01-02", m[1])
+			}
+			remoteItems = append(remoteItems, ri)
+		}
+	}
+
+	var added, updated int
+	tasks, added, updated = applyRemoteItems(tasks, remoteItems)
+	fmt.Printf("%sVault: %d added, %d updated, %d completed here, %d ticked in notes%s\n",
+		green, added, updated, completed, ticked, reset)
+	return tasks, nil
+}
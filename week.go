@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// printDays shows the unfinished tasks due on each of days days starting
+// at from, grouped under a heading per day. Overdue tasks come first when
+// the window includes today.
+func printDays(tasks []Task, from time.Time, days int, now time.Time) {
+	today := logicalDate(now)
+	from = dateOf(from)
+	to := from.AddDate(0, 0, days)
+	pending, _ := sortTasks(tasks, "priority", false)
+
+	if !today.Before(from) && today.Before(to) {
+		if late := overdueTasks(pending, now); len(late) > 0 {
+			fmt.Println(red + "Overdue" + reset)
+			printOverdue(late, now)
+			fmt.Println()
+		}
+	}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		heading := day.Format("Monday 2 January")
+		if day.Equal(today) {
+			heading = yellow + heading + " (today)" + reset
+		}
+		fmt.Println(heading)
+		empty := true
+		for _, task := range pending {
+			if task.Done || task.Deadline.IsZero() || !dateOf(task.Deadline).Equal(day) || isOverdue(task, now) {
+				continue
+			}
+			empty = false
+			prefix := fmt.Sprintf("  #%d: %s", task.ID, priorityLabel(task.Priority))
+			fmt.Printf("%s%s%s\n", prefix, fitTitle(task.Title, visibleLen(prefix+tagLabels(task.Tags))), tagLabels(task.Tags))
+		}
+		if empty {
+			fmt.Println("  Nothing due")
+		}
+	}
+}
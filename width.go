@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// lineWidth is the width titles are truncated to fit; 0 turns it off
+var lineWidth = terminalWidth()
+
+// terminalWidth returns the width of the terminal on stdout, honoring
+// COLUMNS, or 0 when output isn't going to a terminal
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return ttyWidth(os.Stdout)
+}
+
+// escapes matches color codes and OSC 8 hyperlinks, which take no space
+var escapes = regexp.MustCompile("\033\\[[0-9;]*m|\033]8;;[^\033]*\033\\\\")
+
+// visibleLen counts the characters of s a terminal actually shows
+func visibleLen(s string) int {
+	return len([]rune(escapes.ReplaceAllString(s, "")))
+}
+
+// fitTitle shortens a title with an ellipsis so that it fits next to used
+// characters of other output on one line
+func fitTitle(title string, used int) string {
+	room := lineWidth - used
+	if lineWidth == 0 || len([]rune(title)) <= room {
+		return title
+	}
+	if room < 10 {
+		room = 10
+	}
+	return string([]rune(title)[:room-1]) + "…"
+}
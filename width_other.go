@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package main
+
+import "os"
+
+// ttyWidth can't ask the terminal on this platform; set COLUMNS instead
+func ttyWidth(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// wrapDay summarizes the day, offers to move unfinished planned tasks to
+// tomorrow and returns the summary to be kept in the day log
+func wrapDay(tasks []Task, ops []Operation, plan Plan, in io.Reader, now time.Time) ([]Task, string) {
+	today := logicalDate(now)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "## %s\n\nDone:\n", today.Format("2006-01-02"))
+	done := completedTasks(tasks, ops, dayStart(today), now)
+	for _, task := range done {
+		fmt.Fprintf(&summary, "- #%d %s\n", task.ID, task.Title)
+	}
+	if len(done) == 0 {
+		summary.WriteString("- (nothing)\n")
+	}
+	fmt.Print(summary.String())
+
+	var unfinished []Task
+	if plan.isFor(now) {
+		for _, id := range plan.IDs {
+			if task := findTask(tasks, id); task != nil && !task.Done {
+				unfinished = append(unfinished, *task)
+			}
+		}
+	}
+	if len(unfinished) > 0 {
+		summary.WriteString("\nCarried over:\n")
+		fmt.Println("\nUnfinished from today's plan:")
+	}
+	reader := bufio.NewReader(in)
+	for _, task := range unfinished {
+		fmt.Printf("#%d: %s\n", task.ID, task.Title)
+		if askChoice(reader, "  Move to tomorrow? [y/n] ", "yn") == "y" {
+			findTask(tasks, task.ID).Deadline = deadlineOn(tomorrow)
+			fmt.Fprintf(&summary, "- #%d %s (moved to %s)\n", task.ID, task.Title, tomorrow.Format("2006-01-02"))
+		} else {
+			fmt.Fprintf(&summary, "- #%d %s\n", task.ID, task.Title)
+		}
+	}
+	return tasks, summary.String()
+}
+
+// appendDayLog adds a day's summary to daylog.md file
+func appendDayLog(summary string) error {
+	file, err := os.OpenFile(dataPath("daylog.md"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = fmt.Fprintln(file, summary)
+	return err
+}